@@ -0,0 +1,140 @@
+// Package apierr содержит типизированные ошибки предметной области, общие для
+// сервисов menu и order, middleware для HTTP-ответов и перехватчик для статусов gRPC.
+package apierr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind — вид ошибки; по нему middleware выбирает HTTP-статус
+type Kind int
+
+const (
+	KindInternal           Kind = iota // 500, текст причины клиенту не отдаётся
+	KindBadRequest                     // 400
+	KindValidation                     // 400 с ошибками по полям
+	KindForbidden                      // 403
+	KindNotFound                       // 404
+	KindConflict                       // 409
+	KindPreconditionFailed             // 412
+	KindUnprocessable                  // 422
+	KindUpstream                       // 502, недоступен другой сервис
+)
+
+var statusByKind = map[Kind]int{
+	KindInternal:           http.StatusInternalServerError,
+	KindBadRequest:         http.StatusBadRequest,
+	KindValidation:         http.StatusBadRequest,
+	KindForbidden:          http.StatusForbidden,
+	KindNotFound:           http.StatusNotFound,
+	KindConflict:           http.StatusConflict,
+	KindPreconditionFailed: http.StatusPreconditionFailed,
+	KindUnprocessable:      http.StatusUnprocessableEntity,
+	KindUpstream:           http.StatusBadGateway,
+}
+
+// Status — HTTP-статус для вида ошибки
+func (k Kind) Status() int {
+	if status, ok := statusByKind[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error — ошибка предметной области. Code и Args выбирают сообщение из каталога,
+// Err хранит исходную причину только для логов
+type Error struct {
+	Kind   Kind
+	Code   string
+	Args   []interface{}
+	Fields FieldErrors
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.Err)
+	}
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind Kind, code string, args []interface{}) *Error {
+	return &Error{Kind: kind, Code: code, Args: args}
+}
+
+func BadRequest(code string, args ...interface{}) *Error {
+	return newError(KindBadRequest, code, args)
+}
+
+func Forbidden(code string, args ...interface{}) *Error {
+	return newError(KindForbidden, code, args)
+}
+
+func NotFound(code string, args ...interface{}) *Error {
+	return newError(KindNotFound, code, args)
+}
+
+func Conflict(code string, args ...interface{}) *Error {
+	return newError(KindConflict, code, args)
+}
+
+func PreconditionFailed(code string, args ...interface{}) *Error {
+	return newError(KindPreconditionFailed, code, args)
+}
+
+func Unprocessable(code string, args ...interface{}) *Error {
+	return newError(KindUnprocessable, code, args)
+}
+
+// Upstream — другой сервис недоступен или ответил ошибкой
+func Upstream(code string, err error) *Error {
+	return &Error{Kind: KindUpstream, Code: code, Err: err}
+}
+
+// Internal — непредвиденная ошибка (например, базы данных); клиент увидит только internal_error
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Err: err}
+}
+
+// Validation — ошибки по полям запроса
+func Validation(fields FieldErrors) *Error {
+	return &Error{Kind: KindValidation, Code: "validation_failed", Fields: fields}
+}
+
+// From приводит любую ошибку к *Error; всё неизвестное считается внутренней ошибкой
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return Internal(err)
+}
+
+// FieldError — нарушенное правило для поля и его параметр (например, минимальное значение)
+type FieldError struct {
+	Rule  string
+	Param interface{}
+}
+
+// FieldErrors — ошибки валидации по именам полей в JSON
+type FieldErrors map[string]FieldError
+
+// Add запоминает первую ошибку для поля
+func (errs FieldErrors) Add(field, rule string, param interface{}) {
+	if _, exists := errs[field]; !exists {
+		errs[field] = FieldError{Rule: rule, Param: param}
+	}
+}
+
+// Merge добавляет ошибки вложенного объекта с префиксом
+func (errs FieldErrors) Merge(prefix string, nested FieldErrors) {
+	for field, fieldErr := range nested {
+		errs.Add(prefix+field, fieldErr.Rule, fieldErr.Param)
+	}
+}
@@ -0,0 +1,96 @@
+package apierr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testMessages = Messages{
+	"ru": {"dish_not_found": "Блюдо %v не найдено"},
+	"en": {"dish_not_found": "Dish %v not found"},
+}
+
+func serve(t *testing.T, err error, acceptLanguage string) (int, Body) {
+	r := gin.New()
+	r.Use(Middleware(testMessages))
+	r.GET("/", func(c *gin.Context) {
+		Abort(c, err)
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", acceptLanguage)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body Body
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestMiddlewareMapsKindToStatus(t *testing.T) {
+	code, body := serve(t, NotFound("dish_not_found", 7), "en")
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "dish_not_found", body.Error.Code)
+	assert.Equal(t, "Dish 7 not found", body.Error.Message)
+}
+
+func TestMiddlewareDefaultsToRussian(t *testing.T) {
+	_, body := serve(t, NotFound("dish_not_found", 7), "")
+
+	assert.Equal(t, "Блюдо 7 не найдено", body.Error.Message)
+}
+
+func TestMiddlewareHidesInternalErrors(t *testing.T) {
+	code, body := serve(t, errors.New(`pq: relation "orders" does not exist`), "en")
+
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, "internal_error", body.Error.Code)
+	assert.NotContains(t, body.Error.Message, "orders")
+}
+
+func TestMiddlewareValidationFields(t *testing.T) {
+	fields := FieldErrors{}
+	fields.Add("price", "min", 0)
+	fields.Add("price", "required", nil)
+
+	code, body := serve(t, Validation(fields), "en")
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "must be at least 0", body.Error.Fields["price"])
+}
+
+func TestUpstreamStatus(t *testing.T) {
+	code, _ := serve(t, Upstream("menu_unavailable", errors.New("connection refused")), "en")
+
+	assert.Equal(t, http.StatusBadGateway, code)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(testMessages)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("accept-language", "en"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/ckeeper.menu.v1.MenuService/GetDish"}
+
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, NotFound("dish_not_found", 7)
+	})
+
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "Dish 7 not found", st.Message())
+	if assert.Len(t, st.Details(), 1) {
+		assert.Equal(t, "dish_not_found", st.Details()[0].(*errdetails.ErrorInfo).Reason)
+	}
+}
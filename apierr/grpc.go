@@ -0,0 +1,84 @@
+package apierr
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"log/slog"
+	"sort"
+)
+
+var codeByKind = map[Kind]codes.Code{
+	KindInternal:           codes.Internal,
+	KindBadRequest:         codes.InvalidArgument,
+	KindValidation:         codes.InvalidArgument,
+	KindForbidden:          codes.PermissionDenied,
+	KindNotFound:           codes.NotFound,
+	KindConflict:           codes.FailedPrecondition,
+	KindPreconditionFailed: codes.Aborted,
+	KindUnprocessable:      codes.InvalidArgument,
+	KindUpstream:           codes.Unavailable,
+}
+
+// GRPCCode — код статуса gRPC для вида ошибки
+func (k Kind) GRPCCode() codes.Code {
+	if code, ok := codeByKind[k]; ok {
+		return code
+	}
+	return codes.Internal
+}
+
+// UnaryServerInterceptor — аналог Middleware для gRPC: ошибка обработчика превращается
+// в статус с кодом по её виду и локализованным сообщением (язык берётся из метаданных
+// accept-language). Код ошибки и ошибки полей передаются в деталях статуса
+func UnaryServerInterceptor(messages Messages) grpc.UnaryServerInterceptor {
+	catalog := messages.merge()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		apiErr := From(err)
+		if apiErr.Kind == KindInternal || apiErr.Kind == KindUpstream {
+			slog.ErrorContext(ctx, "grpc request failed", "method", info.FullMethod, "error", apiErr.Error())
+		}
+
+		lang := supportedLanguages[0]
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("accept-language"); len(values) > 0 {
+				lang = matchLanguage(values[0])
+			}
+		}
+		response := catalog.response(lang, apiErr)
+
+		st := status.New(apiErr.Kind.GRPCCode(), response.Message)
+		details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: response.Code}}
+		if len(response.Fields) > 0 {
+			badRequest := &errdetails.BadRequest{}
+			fields := make([]string, 0, len(response.Fields))
+			for field := range response.Fields {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			for _, field := range fields {
+				badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       field,
+					Description: response.Fields[field],
+				})
+			}
+			details = append(details, badRequest)
+		}
+		if withDetails, err := st.WithDetails(details...); err == nil {
+			st = withDetails
+		}
+		return nil, st.Err()
+	}
+}
@@ -0,0 +1,89 @@
+package apierr
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// Messages — каталог сообщений: язык -> код -> шаблон для fmt.Sprintf
+type Messages map[string]map[string]string
+
+// Языки ответов; первый используется, если Accept-Language не передан или не поддерживается
+var (
+	supportedLanguages = []string{"ru", "en"}
+	languageMatcher    = language.NewMatcher([]language.Tag{language.Russian, language.English})
+)
+
+// Общие для всех сервисов сообщения
+var commonMessages = Messages{
+	"ru": {
+		"invalid_body":      "Некорректное тело запроса",
+		"validation_failed": "Ошибка валидации",
+		"internal_error":    "Внутренняя ошибка сервера",
+
+		"field_required":     "обязательное поле",
+		"field_min":          "должно быть не меньше %v",
+		"field_max_length":   "должно быть не длиннее %v символов",
+		"field_invalid_type": "неверный тип значения",
+		"field_unknown":      "неизвестное или недоступное для изменения поле",
+		"field_one_of":       "допустимые значения: %v",
+		"field_duplicate":    "значение повторяется",
+	},
+	"en": {
+		"invalid_body":      "Invalid request body",
+		"validation_failed": "Validation failed",
+		"internal_error":    "Internal server error",
+
+		"field_required":     "is required",
+		"field_min":          "must be at least %v",
+		"field_max_length":   "must be at most %v characters",
+		"field_invalid_type": "has invalid type",
+		"field_unknown":      "is unknown or read-only",
+		"field_one_of":       "must be one of: %v",
+		"field_duplicate":    "is duplicated",
+	},
+}
+
+// merge возвращает общий каталог, дополненный сообщениями сервиса
+func (m Messages) merge() Messages {
+	merged := Messages{}
+	for _, catalog := range []Messages{commonMessages, m} {
+		for lang, codes := range catalog {
+			if merged[lang] == nil {
+				merged[lang] = map[string]string{}
+			}
+			for code, template := range codes {
+				merged[lang][code] = template
+			}
+		}
+	}
+	return merged
+}
+
+// Localize возвращает текст сообщения на нужном языке
+func (m Messages) Localize(lang, code string, args ...interface{}) string {
+	template, ok := m[lang][code]
+	if !ok {
+		return code
+	}
+	if len(args) == 0 {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// RequestLanguage — язык ответа по заголовку Accept-Language
+func RequestLanguage(c *gin.Context) string {
+	return matchLanguage(c.GetHeader("Accept-Language"))
+}
+
+// matchLanguage выбирает поддерживаемый язык по значению Accept-Language
+func matchLanguage(acceptLanguage string) string {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, confidence := languageMatcher.Match(tags...)
+	if confidence == language.No {
+		return supportedLanguages[0]
+	}
+	return supportedLanguages[index]
+}
@@ -0,0 +1,63 @@
+package apierr
+
+import (
+	"github.com/gin-gonic/gin"
+	"log/slog"
+)
+
+// Body — тело ответа об ошибке: {"error": {"code": ..., "message": ..., "fields": {...}}}
+type Body struct {
+	Error Response `json:"error"`
+}
+
+type Response struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Abort прерывает обработку запроса с ошибкой; ответ сформирует Middleware
+func Abort(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// Middleware превращает ошибку, переданную через Abort или c.Error, в JSON-ответ
+// с HTTP-статусом по её виду. Причины внутренних ошибок только логируются
+func Middleware(messages Messages) gin.HandlerFunc {
+	catalog := messages.merge()
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		apiErr := From(c.Errors.Last().Err)
+		if apiErr.Kind == KindInternal || apiErr.Kind == KindUpstream {
+			slog.ErrorContext(c.Request.Context(), "request failed", "method", c.Request.Method, "path", c.Request.URL.Path, "error", apiErr.Error())
+		}
+
+		response := catalog.response(RequestLanguage(c), apiErr)
+		c.JSON(apiErr.Kind.Status(), Body{Error: response})
+	}
+}
+
+// response — локализованное тело ошибки с сообщениями по полям
+func (m Messages) response(lang string, apiErr *Error) Response {
+	response := Response{
+		Code:    apiErr.Code,
+		Message: m.Localize(lang, apiErr.Code, apiErr.Args...),
+	}
+	if len(apiErr.Fields) > 0 {
+		response.Fields = make(map[string]string, len(apiErr.Fields))
+		for field, fieldErr := range apiErr.Fields {
+			if fieldErr.Param != nil {
+				response.Fields[field] = m.Localize(lang, "field_"+fieldErr.Rule, fieldErr.Param)
+			} else {
+				response.Fields[field] = m.Localize(lang, "field_"+fieldErr.Rule)
+			}
+		}
+	}
+	return response
+}
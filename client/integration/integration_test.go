@@ -0,0 +1,156 @@
+//go:build integration
+
+// Интеграционные тесты против запущенной системы (make integration).
+// Адреса сервисов берутся из MENU_URL и ORDER_URL.
+package integration
+
+import (
+	"c_keeper_go/client/menuclient"
+	"c_keeper_go/client/orderclient"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func serviceURL(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func clients(t *testing.T) (*menuclient.ClientWithResponses, *orderclient.ClientWithResponses) {
+	t.Helper()
+	menu, err := menuclient.NewClientWithResponses(serviceURL("MENU_URL", "http://localhost:5003"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := orderclient.NewClientWithResponses(serviceURL("ORDER_URL", "http://localhost:5004"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ждём, пока сервисы и их зависимости будут готовы
+	ctx := context.Background()
+	deadline := time.Now().Add(time.Minute)
+	for {
+		menuReady, menuErr := menu.GetReadyzWithResponse(ctx)
+		orderReady, orderErr := order.GetReadyzWithResponse(ctx)
+		if menuErr == nil && orderErr == nil &&
+			menuReady.StatusCode() == http.StatusOK && orderReady.StatusCode() == http.StatusOK {
+			return menu, order
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("сервисы не готовы: menu %v, order %v", menuErr, orderErr)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// Блюдо с уникальным названием, чтобы прогоны не мешали друг другу
+func createDish(t *testing.T, menu *menuclient.ClientWithResponses, quantity int) menuclient.Dish {
+	t.Helper()
+	resp, err := menu.AddDishWithResponse(context.Background(), menuclient.DishInput{
+		Name:              fmt.Sprintf("Борщ %d", time.Now().UnixNano()),
+		Price:             ptr(float64(350)),
+		Description:       ptr("Интеграционный тест"),
+		AvailableQuantity: ptr(quantity),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.JSON201 == nil {
+		t.Fatalf("блюдо не создано: %d %s", resp.StatusCode(), resp.Body)
+	}
+	return *resp.JSON201
+}
+
+func stock(t *testing.T, menu *menuclient.ClientWithResponses, id int) int {
+	t.Helper()
+	resp, err := menu.GetDishWithResponse(context.Background(), id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.JSON200 == nil {
+		t.Fatalf("блюдо %d не получено: %d %s", id, resp.StatusCode(), resp.Body)
+	}
+	return resp.JSON200.AvailableQuantity
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	menu, order := clients(t)
+	ctx := context.Background()
+	dish := createDish(t, menu, 10)
+
+	// Заказ резервирует порции в menu через gRPC
+	created, err := order.CreateOrderWithResponse(ctx, nil, orderclient.OrderInput{MenuId: dish.Id, Quantity: 3, TableId: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.JSON201 == nil {
+		t.Fatalf("заказ не создан: %d %s", created.StatusCode(), created.Body)
+	}
+	placed := created.JSON201.Order
+	if placed.TotalPrice != 3*350 {
+		t.Errorf("сумма заказа %v, ожидалось %v", placed.TotalPrice, 3*350)
+	}
+	if got := stock(t, menu, dish.Id); got != 7 {
+		t.Errorf("остаток после заказа %d, ожидалось 7", got)
+	}
+
+	// Описание блюда по заказу берётся из menu
+	description, err := order.GetDishDescriptionWithResponse(ctx, placed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if description.JSON200 == nil || description.JSON200.Description != "Интеграционный тест" {
+		t.Errorf("неверное описание блюда: %d %s", description.StatusCode(), description.Body)
+	}
+
+	// Кухня берёт заказ в работу
+	updated, err := order.UpdateOrderStatusWithResponse(ctx, placed.ID, nil, orderclient.StatusUpdate{Status: orderclient.OrderStatusInProgress})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.JSON200 == nil || updated.JSON200.Order.Status != orderclient.OrderStatusInProgress {
+		t.Fatalf("статус не изменён: %d %s", updated.StatusCode(), updated.Body)
+	}
+
+	// Отмена до приготовления возвращает порции
+	cancelled, err := order.CancelOrderWithResponse(ctx, placed.ID, nil, orderclient.CancelRequest{
+		Reason:      orderclient.GuestChangedMind,
+		CancelledBy: "integration",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.JSON200 == nil || !cancelled.JSON200.StockReturned {
+		t.Fatalf("заказ не отменён: %d %s", cancelled.StatusCode(), cancelled.Body)
+	}
+	if got := stock(t, menu, dish.Id); got != 10 {
+		t.Errorf("остаток после отмены %d, ожидалось 10", got)
+	}
+}
+
+func TestOrderRejectedWhenOutOfStock(t *testing.T) {
+	menu, order := clients(t)
+	dish := createDish(t, menu, 1)
+
+	resp, err := order.CreateOrderWithResponse(context.Background(), nil, orderclient.OrderInput{MenuId: dish.Id, Quantity: 2, TableId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusConflict {
+		t.Errorf("ожидался 409, получено %d: %s", resp.StatusCode(), resp.Body)
+	}
+	if got := stock(t, menu, dish.Id); got != 1 {
+		t.Errorf("остаток изменился: %d", got)
+	}
+}
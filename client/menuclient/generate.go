@@ -0,0 +1,4 @@
+// Package menuclient — HTTP-клиент сервиса menu, сгенерированный по menu/openapi.json.
+package menuclient
+
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen -config oapi-codegen.yaml ../../menu/openapi.json
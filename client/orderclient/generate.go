@@ -0,0 +1,4 @@
+// Package orderclient — HTTP-клиент сервиса order, сгенерированный по order/openapi.json.
+package orderclient
+
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen -config oapi-codegen.yaml ../../order/openapi.json
@@ -0,0 +1,55 @@
+package orderclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateOrderWithResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/order" {
+			t.Errorf("неожиданный запрос %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Idempotency-Key") != "key-1" {
+			t.Errorf("Idempotency-Key не передан")
+		}
+		var input OrderInput
+		json.NewDecoder(r.Body).Decode(&input)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(OrderResult{
+			Message: "Заказ успешно создан",
+			Order: Order{
+				ID:          1,
+				OrderNumber: "ORD20250101-001",
+				MenuId:      input.MenuId,
+				Quantity:    input.Quantity,
+				TableId:     input.TableId,
+				Status:      OrderStatusPending,
+				Version:     1,
+			},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "key-1"
+	resp, err := client.CreateOrderWithResponse(context.Background(), &CreateOrderParams{IdempotencyKey: &key}, OrderInput{MenuId: 1, Quantity: 2, TableId: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusCreated || resp.JSON201 == nil {
+		t.Fatalf("ожидался 201 с заказом, получено %d: %s", resp.StatusCode(), resp.Body)
+	}
+	if resp.JSON201.Order.Quantity != 2 || resp.JSON201.Order.Status != OrderStatusPending {
+		t.Errorf("неверный заказ в ответе: %+v", resp.JSON201.Order)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build tools
+
+// Генератор клиентов фиксируется в go.mod, чтобы go generate давал одинаковый результат
+package client
+
+import _ "github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen"
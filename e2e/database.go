@@ -0,0 +1,57 @@
+package e2e
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"os"
+	"testing"
+)
+
+// Подключение к Postgres, в котором создаются одноразовые базы
+func adminDSN() string {
+	if dsn := os.Getenv("E2E_DATABASE_DSN"); dsn != "" {
+		return dsn
+	}
+	return "host=localhost user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"
+}
+
+// Создаёт пустую базу для одного теста и удаляет её по завершении; возвращает DSN для сервисов
+func createDatabase(t testing.TB) string {
+	t.Helper()
+	ctx := context.Background()
+	config, err := pgx.ParseConfig(adminDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Skipf("Postgres недоступен: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	name := "c_keeper_e2e_" + hex.EncodeToString(suffix)
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		t.Fatal(err)
+	}
+
+	// Регистрируется раньше сервисов, поэтому выполняется после их остановки
+	t.Cleanup(func() {
+		conn, err := pgx.ConnectConfig(ctx, config)
+		if err != nil {
+			t.Logf("база %s не удалена: %v", name, err)
+			return
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)"); err != nil {
+			t.Logf("база %s не удалена: %v", name, err)
+		}
+	})
+
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		config.Host, config.Port, config.User, config.Password, name)
+}
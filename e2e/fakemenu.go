@@ -0,0 +1,98 @@
+package e2e
+
+import (
+	menuv1 "c_keeper_go/proto/menu/v1"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// FakeMenu — сервис menu в процессе теста: отвечает по gRPC как настоящий,
+// хранит блюда в памяти и позволяет проверить остатки
+type FakeMenu struct {
+	menuv1.UnimplementedMenuServiceServer
+	mu     sync.Mutex
+	dishes map[uint32]*menuv1.Dish
+}
+
+func NewFakeMenu() *FakeMenu {
+	return &FakeMenu{dishes: map[uint32]*menuv1.Dish{}}
+}
+
+// AddDish добавляет или заменяет блюдо
+func (f *FakeMenu) AddDish(dish *menuv1.Dish) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dishes[dish.GetId()] = proto.Clone(dish).(*menuv1.Dish)
+}
+
+// Stock — текущий остаток порций блюда
+func (f *FakeMenu) Stock(id uint32) int32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.dishes[id].GetAvailableQuantity()
+}
+
+func (f *FakeMenu) ListDishes(ctx context.Context, req *menuv1.ListDishesRequest) (*menuv1.ListDishesResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &menuv1.ListDishesResponse{}
+	for _, dish := range f.dishes {
+		resp.Dishes = append(resp.Dishes, proto.Clone(dish).(*menuv1.Dish))
+	}
+	sort.Slice(resp.Dishes, func(i, j int) bool { return resp.Dishes[i].GetId() < resp.Dishes[j].GetId() })
+	return resp, nil
+}
+
+func (f *FakeMenu) GetDish(ctx context.Context, req *menuv1.GetDishRequest) (*menuv1.Dish, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dish, ok := f.dishes[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "блюдо не найдено")
+	}
+	return proto.Clone(dish).(*menuv1.Dish), nil
+}
+
+func (f *FakeMenu) AdjustStock(ctx context.Context, req *menuv1.AdjustStockRequest) (*menuv1.Dish, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dish, ok := f.dishes[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "блюдо не найдено")
+	}
+	if dish.GetAvailableQuantity()+req.GetDelta() < 0 {
+		return nil, status.Error(codes.FailedPrecondition, "недостаточно порций")
+	}
+	dish.AvailableQuantity += req.GetDelta()
+	return proto.Clone(dish).(*menuv1.Dish), nil
+}
+
+// ConsumeStock — у блюд без рецепта списание не меняет остаток: порции уже зарезервированы
+func (f *FakeMenu) ConsumeStock(ctx context.Context, req *menuv1.ConsumeStockRequest) (*menuv1.Dish, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dish, ok := f.dishes[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "блюдо не найдено")
+	}
+	return proto.Clone(dish).(*menuv1.Dish), nil
+}
+
+// Запуск gRPC-сервера на случайном порту; возвращает его адрес
+func (f *FakeMenu) start(t testing.TB) string {
+	t.Helper()
+	server := grpc.NewServer()
+	menuv1.RegisterMenuServiceServer(server, f)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(menuv1.MenuService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	return serveGRPC(t, server)
+}
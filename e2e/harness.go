@@ -0,0 +1,97 @@
+// Package e2e поднимает систему целиком для сквозных тестов: сервисы menu и order
+// на случайных портах, одноразовую базу в Postgres и, при необходимости, поддельный
+// menu вместо настоящего.
+//
+// Сервисы запускаются в процессе теста через их конструкторы HTTP- и gRPC-серверов.
+// Состояние сервиса (база, клиенты) хранится в переменных его пакета, поэтому
+// одновременно работает только одна система: тесты с Start не должны быть параллельными.
+package e2e
+
+import (
+	"c_keeper_go/client/menuclient"
+	"c_keeper_go/client/orderclient"
+	"google.golang.org/grpc"
+	"menu"
+	"net"
+	"net/http/httptest"
+	"order"
+	"testing"
+)
+
+// Options настраивает запуск системы
+type Options struct {
+	// FakeMenu подменяет сервис menu: order ходит к нему по gRPC, настоящий menu не запускается
+	FakeMenu *FakeMenu
+}
+
+// Harness — запущенная система и клиенты её публичных API
+type Harness struct {
+	DatabaseDSN   string
+	MenuURL       string // пусто, если вместо menu работает FakeMenu
+	OrderURL      string
+	OrderGRPCAddr string
+
+	Menu  *menuclient.ClientWithResponses // nil, если вместо menu работает FakeMenu
+	Order *orderclient.ClientWithResponses
+}
+
+// Start запускает систему; всё останавливается и удаляется по завершении теста.
+// Без доступного Postgres (E2E_DATABASE_DSN) тест пропускается
+func Start(t testing.TB, opts Options) *Harness {
+	t.Helper()
+	h := &Harness{DatabaseDSN: createDatabase(t)}
+
+	// Адрес order нужен menu при настройке, поэтому порт order занимается заранее
+	orderHTTP := httptest.NewUnstartedServer(nil)
+	h.OrderURL = "http://" + orderHTTP.Listener.Addr().String()
+
+	var menuGRPCAddr string
+	if opts.FakeMenu != nil {
+		menuGRPCAddr = opts.FakeMenu.start(t)
+	} else {
+		if err := menu.Setup(menu.Config{DatabaseDSN: h.DatabaseDSN, OrderServiceURL: h.OrderURL}); err != nil {
+			t.Fatalf("запуск menu: %v", err)
+		}
+		// Соединения с базой закрываются после остановки серверов, а база удаляется последней
+		t.Cleanup(menu.Close)
+		menuHTTP := httptest.NewServer(menu.NewRouter())
+		t.Cleanup(menuHTTP.Close)
+		menuGRPCAddr = serveGRPC(t, menu.NewGRPCServer())
+
+		h.MenuURL = menuHTTP.URL
+		client, err := menuclient.NewClientWithResponses(h.MenuURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Menu = client
+	}
+
+	if err := order.Setup(order.Config{DatabaseDSN: h.DatabaseDSN, MenuGRPCAddr: menuGRPCAddr}); err != nil {
+		orderHTTP.Close()
+		t.Fatalf("запуск order: %v", err)
+	}
+	t.Cleanup(order.Close)
+	orderHTTP.Config.Handler = order.NewRouter()
+	orderHTTP.Start()
+	t.Cleanup(orderHTTP.Close)
+	h.OrderGRPCAddr = serveGRPC(t, order.NewGRPCServer())
+
+	client, err := orderclient.NewClientWithResponses(h.OrderURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Order = client
+	return h
+}
+
+// Запуск gRPC-сервера на свободном порту localhost; возвращает его адрес
+func serveGRPC(t testing.TB, server *grpc.Server) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
@@ -0,0 +1,257 @@
+package e2e
+
+import (
+	"c_keeper_go/client/menuclient"
+	"c_keeper_go/client/orderclient"
+	menuv1 "c_keeper_go/proto/menu/v1"
+	"context"
+	"net/http"
+	"testing"
+)
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func addDish(t *testing.T, h *Harness, input menuclient.DishInput) menuclient.Dish {
+	t.Helper()
+	resp, err := h.Menu.AddDishWithResponse(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.JSON201 == nil {
+		t.Fatalf("блюдо не создано: %d %s", resp.StatusCode(), resp.Body)
+	}
+	return *resp.JSON201
+}
+
+func placeOrder(t *testing.T, h *Harness, input orderclient.OrderInput) orderclient.Order {
+	t.Helper()
+	resp, err := h.Order.CreateOrderWithResponse(context.Background(), nil, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.JSON201 == nil {
+		t.Fatalf("заказ не создан: %d %s", resp.StatusCode(), resp.Body)
+	}
+	return resp.JSON201.Order
+}
+
+func setStatus(t *testing.T, h *Harness, id int, status orderclient.OrderStatus) orderclient.Order {
+	t.Helper()
+	resp, err := h.Order.UpdateOrderStatusWithResponse(context.Background(), id, nil, orderclient.StatusUpdate{
+		Status:    status,
+		ChangedBy: ptr("kitchen"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.JSON200 == nil {
+		t.Fatalf("статус заказа %d не изменён: %d %s", id, resp.StatusCode(), resp.Body)
+	}
+	return resp.JSON200.Order
+}
+
+func stock(t *testing.T, h *Harness, id int) int {
+	t.Helper()
+	resp, err := h.Menu.GetDishWithResponse(context.Background(), id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.JSON200 == nil {
+		t.Fatalf("блюдо %d не получено: %d %s", id, resp.StatusCode(), resp.Body)
+	}
+	return resp.JSON200.AvailableQuantity
+}
+
+// Полный путь заказа через оба сервиса: смена → блюда → заказ → кухня → оплата → закрытие смены
+func TestRestaurantFlow(t *testing.T) {
+	h := Start(t, Options{})
+	ctx := context.Background()
+
+	// Менеджер открывает смену и заводит блюда
+	manager := orderclient.OpenShiftParamsXUserRoleManager
+	closer := orderclient.CloseShiftParamsXUserRoleManager
+	shift, err := h.Order.OpenShiftWithResponse(ctx, &orderclient.OpenShiftParams{XUserRole: &manager},
+		orderclient.ShiftOpen{OpeningCash: 5000, OpenedBy: "manager"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shift.JSON201 == nil {
+		t.Fatalf("смена не открыта: %d %s", shift.StatusCode(), shift.Body)
+	}
+	borscht := addDish(t, h, menuclient.DishInput{
+		Name:              "Борщ",
+		Price:             ptr(float64(350)),
+		Description:       ptr("Классический борщ со сметаной"),
+		AvailableQuantity: ptr(20),
+		Modifiers: &[]menuclient.ModifierInput{
+			{Name: "Большая порция", Type: menuclient.ModifierInputTypeSize, PriceDelta: ptr(float64(100))},
+			{Name: "Без сметаны", Type: menuclient.ModifierInputTypeRemoval},
+		},
+	})
+	pelmeni := addDish(t, h, menuclient.DishInput{
+		Name:              "Пельмени",
+		Price:             ptr(float64(420)),
+		AvailableQuantity: ptr(10),
+	})
+	large := (*borscht.Modifiers)[0].Id
+
+	// Официант принимает заказы стола 5; порции резервируются в menu
+	soup := placeOrder(t, h, orderclient.OrderInput{MenuId: borscht.Id, Quantity: 2, TableId: 5, ModifierIds: &[]int{large}})
+	dumplings := placeOrder(t, h, orderclient.OrderInput{MenuId: pelmeni.Id, Quantity: 1, TableId: 5, Notes: ptr("Со сметаной")})
+	if soup.TotalPrice != 2*450 || dumplings.TotalPrice != 420 {
+		t.Errorf("неверные суммы заказов: %v и %v", soup.TotalPrice, dumplings.TotalPrice)
+	}
+	if got := stock(t, h, borscht.Id); got != 18 {
+		t.Errorf("остаток борща %d, ожидалось 18", got)
+	}
+
+	// Кухня получает тикет и готовит
+	ticket, err := h.Order.GetKitchenTicketWithResponse(ctx, soup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.JSON200 == nil || ticket.JSON200.Dish != "Борщ" || ticket.JSON200.Quantity != 2 {
+		t.Fatalf("неверный тикет: %d %s", ticket.StatusCode(), ticket.Body)
+	}
+	for _, order := range []orderclient.Order{soup, dumplings} {
+		setStatus(t, h, order.ID, orderclient.OrderStatusInProgress)
+		setStatus(t, h, order.ID, orderclient.OrderStatusCompleted)
+	}
+
+	// Стол рассчитывается: оплата закрывает все его завершённые заказы смены
+	payment, err := h.Order.CreatePaymentWithResponse(ctx, nil, orderclient.PaymentInput{
+		TableId:  5,
+		Tender:   orderclient.Cash,
+		Discount: ptr(float64(20)),
+		PaidBy:   "waiter",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.JSON201 == nil {
+		t.Fatalf("счёт не оплачен: %d %s", payment.StatusCode(), payment.Body)
+	}
+	if payment.JSON201.Subtotal != 900+420 || payment.JSON201.Amount != 900+420-20 {
+		t.Errorf("счёт стола %v, к оплате %v", payment.JSON201.Subtotal, payment.JSON201.Amount)
+	}
+
+	// Завершённый заказ отменить нельзя, порции остаются списанными
+	cancelled, err := h.Order.CancelOrderWithResponse(ctx, soup.ID, nil, orderclient.CancelRequest{
+		Reason:      orderclient.GuestChangedMind,
+		CancelledBy: "waiter",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.StatusCode() != http.StatusConflict {
+		t.Errorf("ожидался 409, получено %d: %s", cancelled.StatusCode(), cancelled.Body)
+	}
+	if got := stock(t, h, borscht.Id); got != 18 {
+		t.Errorf("остаток борща %d, ожидалось 18", got)
+	}
+
+	// Менеджер закрывает смену: в кассе 5000 + 1300 наличными
+	closed, err := h.Order.CloseShiftWithResponse(ctx, &orderclient.CloseShiftParams{XUserRole: &closer},
+		orderclient.ShiftClose{CountedCash: 6300, ClosedBy: "manager"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.JSON200 == nil || closed.JSON200.Report == nil {
+		t.Fatalf("смена не закрыта: %d %s", closed.StatusCode(), closed.Body)
+	}
+	if report := closed.JSON200.Report; report.NetSales != 1300 || *report.CashDiscrepancy != 0 {
+		t.Errorf("неверный Z-отчёт: %s", closed.Body)
+	}
+}
+
+// order с поддельным menu: можно проверить поведение при нехватке порций без настоящего сервиса
+func TestOrderWithFakeMenu(t *testing.T) {
+	menu := NewFakeMenu()
+	menu.AddDish(&menuv1.Dish{Id: 1, Name: "Солянка", Price: 380, AvailableQuantity: 3})
+	h := Start(t, Options{FakeMenu: menu})
+	ctx := context.Background()
+
+	order := placeOrder(t, h, orderclient.OrderInput{MenuId: 1, Quantity: 2, TableId: 2})
+	if menu.Stock(1) != 1 {
+		t.Errorf("остаток %d, ожидалось 1", menu.Stock(1))
+	}
+
+	resp, err := h.Order.CreateOrderWithResponse(ctx, nil, orderclient.OrderInput{MenuId: 1, Quantity: 2, TableId: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusConflict {
+		t.Errorf("ожидался 409, получено %d: %s", resp.StatusCode(), resp.Body)
+	}
+
+	// Отмена до приготовления возвращает порции в menu
+	cancelled, err := h.Order.CancelOrderWithResponse(ctx, order.ID, nil, orderclient.CancelRequest{
+		Reason:      orderclient.GuestChangedMind,
+		CancelledBy: "waiter",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.JSON200 == nil || !cancelled.JSON200.StockReturned {
+		t.Fatalf("заказ не отменён: %d %s", cancelled.StatusCode(), cancelled.Body)
+	}
+	if menu.Stock(1) != 3 {
+		t.Errorf("остаток %d, ожидалось 3", menu.Stock(1))
+	}
+}
+
+// Отмена приготовленного заказа списывает ингредиенты, а не оставляет их в резерве
+func TestCancelCookedOrderReleasesReserve(t *testing.T) {
+	h := Start(t, Options{})
+	ctx := context.Background()
+
+	flour, err := h.Menu.AddIngredientWithResponse(ctx, menuclient.IngredientInput{
+		Name:  "Мука",
+		Unit:  menuclient.IngredientInputUnitG,
+		Stock: ptr(float64(1000)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flour.JSON201 == nil {
+		t.Fatalf("ингредиент не создан: %d %s", flour.StatusCode(), flour.Body)
+	}
+	pancakes := addDish(t, h, menuclient.DishInput{Name: "Блины", Price: ptr(float64(200))})
+	recipe, err := h.Menu.SetRecipeWithResponse(ctx, pancakes.Id, menuclient.SetRecipeJSONRequestBody{
+		{IngredientId: flour.JSON201.Id, Quantity: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipe.StatusCode() != http.StatusOK {
+		t.Fatalf("рецепт не сохранён: %d %s", recipe.StatusCode(), recipe.Body)
+	}
+
+	order := placeOrder(t, h, orderclient.OrderInput{MenuId: pancakes.Id, Quantity: 2, TableId: 1})
+	cancelled, err := h.Order.CancelOrderWithResponse(ctx, order.ID, nil, orderclient.CancelRequest{
+		Reason:      orderclient.KitchenError,
+		CancelledBy: "chef",
+		Cooked:      ptr(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.JSON200 == nil || cancelled.JSON200.StockReturned {
+		t.Fatalf("заказ не отменён: %d %s", cancelled.StatusCode(), cancelled.Body)
+	}
+
+	ingredients, err := h.Menu.GetIngredientsWithResponse(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingredients.JSON200 == nil {
+		t.Fatalf("ингредиенты не получены: %d %s", ingredients.StatusCode(), ingredients.Body)
+	}
+	for _, ingredient := range *ingredients.JSON200 {
+		if ingredient.Id == flour.JSON201.Id && (ingredient.Reserved != 0 || ingredient.Stock != 800) {
+			t.Errorf("мука: остаток %v, резерв %v; ожидалось 800 и 0", ingredient.Stock, ingredient.Reserved)
+		}
+	}
+}
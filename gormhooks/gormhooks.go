@@ -0,0 +1,37 @@
+// Package gormhooks регистрирует колбэки GORM вокруг каждой операции с базой;
+// на нём построены метрики и трассировка запросов.
+package gormhooks
+
+import "gorm.io/gorm"
+
+// Hook вызывается до или после операции GORM (create, query, update, delete, row, raw)
+type Hook func(operation string, tx *gorm.DB)
+
+// Around регистрирует before и after вокруг каждой операции под именами
+// "<name>:before_<операция>" и "<name>:after_<операция>"
+func Around(db *gorm.DB, name string, before, after Hook) error {
+	callbacks := db.Callback()
+	type registerFunc func(name string, fn func(*gorm.DB)) error
+	processors := []struct {
+		operation     string
+		before, after registerFunc
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+
+	for _, p := range processors {
+		operation := p.operation
+		if err := p.before(name+":before_"+operation, func(tx *gorm.DB) { before(operation, tx) }); err != nil {
+			return err
+		}
+		if err := p.after(name+":after_"+operation, func(tx *gorm.DB) { after(operation, tx) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Package health содержит проверки живости и готовности сервиса (/healthz, /readyz)
+// для Docker и оркестратора.
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gorm.io/gorm"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check проверяет одну зависимость сервиса; nil — зависимость доступна
+type Check func(ctx context.Context) error
+
+// Ограничение времени всех проверок готовности
+var checkTimeout = 2 * time.Second
+
+// Checker отвечает на пробы живости и готовности
+type Checker struct {
+	names    []string
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+func New() *Checker {
+	return &Checker{checks: map[string]Check{}}
+}
+
+// Add добавляет зависимость в проверку готовности
+func (h *Checker) Add(name string, check Check) {
+	h.names = append(h.names, name)
+	h.checks[name] = check
+}
+
+// Shutdown переводит сервис в «не готов»: балансировщик перестаёт слать новые запросы,
+// пока начатые дорабатывают
+func (h *Checker) Shutdown() {
+	h.draining.Store(true)
+}
+
+// Liveness (GET /healthz): процесс жив и обрабатывает запросы
+func (h *Checker) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness (GET /readyz): все зависимости доступны; при недоступности — 503 с причинами
+func (h *Checker) Readiness(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), checkTimeout)
+	defer cancel()
+
+	// Зависимости проверяются параллельно, чтобы медленная не задерживала остальные
+	results := make([]error, len(h.names))
+	var wg sync.WaitGroup
+	for i, name := range h.names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}(i, h.checks[name])
+	}
+	wg.Wait()
+
+	status, code := "ok", http.StatusOK
+	checks := map[string]string{}
+	for i, name := range h.names {
+		checks[name] = "ok"
+		if results[i] != nil {
+			checks[name] = results[i].Error()
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+// DB проверяет соединение с базой данных
+func DB(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// GRPC проверяет сервис по стандартному протоколу grpc.health.v1
+func GRPC(conn grpc.ClientConnInterface, service string) Check {
+	client := healthpb.NewHealthClient(conn)
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("статус %s", resp.GetStatus())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func probe(checker *Checker, path string) (int, map[string]interface{}) {
+	r := gin.New()
+	r.GET("/healthz", checker.Liveness)
+	r.GET("/readyz", checker.Readiness)
+
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func TestReadinessReportsFailedDependency(t *testing.T) {
+	checker := New()
+	checker.Add("postgres", func(ctx context.Context) error { return nil })
+	checker.Add("menu", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	code, body := probe(checker, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", body["status"])
+	assert.Equal(t, map[string]interface{}{"postgres": "ok", "menu": "connection refused"}, body["checks"])
+
+	// Живость от зависимостей не зависит
+	code, _ = probe(checker, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestReadinessDuringShutdown(t *testing.T) {
+	checker := New()
+	checker.Add("postgres", func(ctx context.Context) error { return nil })
+
+	code, _ := probe(checker, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	checker.Shutdown()
+	code, body := probe(checker, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting_down", body["status"])
+}
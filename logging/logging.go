@@ -0,0 +1,102 @@
+// Package logging настраивает структурированные JSON-логи (slog) сервисов и
+// сквозной идентификатор запроса X-Request-ID для HTTP и gRPC.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Заголовок HTTP и ключ метаданных gRPC с идентификатором запроса
+const (
+	RequestIDHeader   = "X-Request-ID"
+	requestIDMetadata = "x-request-id"
+)
+
+type requestIDKey struct{}
+
+// Setup делает JSON-логгер сервиса логгером по умолчанию. Уровень задаётся LOG_LEVEL
+// (debug, info, warn, error), по умолчанию info
+func Setup(service string) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(os.Getenv("LOG_LEVEL")))); err != nil {
+		level = slog.LevelInfo
+	}
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	logger := slog.New(contextHandler{handler}).With("service", service)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Fatal пишет ошибку в лог и завершает процесс
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// WithRequestID сохраняет идентификатор запроса в контексте
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID — идентификатор запроса из контекста или пустая строка
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID — случайный идентификатор для запроса без X-Request-ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestIDOrNew — переданный идентификатор, если он корректен, иначе новый
+func requestIDOrNew(id string) string {
+	if validRequestID(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+// validRequestID отсекает слишком длинные и непечатные значения из заголовка
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// contextHandler добавляет request_id и trace_id из контекста в каждую запись (slog.InfoContext и т.п.)
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
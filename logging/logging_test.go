@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(requestID string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, RequestID(c.Request.Context()))
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	if requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddlewareKeepsIncomingRequestID(t *testing.T) {
+	w := serve("guest-complaint-42")
+
+	assert.Equal(t, "guest-complaint-42", w.Body.String())
+	assert.Equal(t, "guest-complaint-42", w.Header().Get(RequestIDHeader))
+}
+
+func TestMiddlewareGeneratesRequestID(t *testing.T) {
+	w := serve("")
+	assert.Len(t, w.Body.String(), 32)
+	assert.Equal(t, w.Body.String(), w.Header().Get(RequestIDHeader))
+
+	// Непечатные символы в заголовке не попадают в логи
+	w = serve("bad id\x01")
+	assert.Len(t, w.Body.String(), 32)
+}
+
+func TestLogRecordsIncludeRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(contextHandler{slog.NewJSONHandler(&buf, nil)}).With("service", "order")
+
+	logger.InfoContext(WithRequestID(context.Background(), "abc"), "order created")
+
+	assert.Contains(t, buf.String(), `"request_id":"abc"`)
+	assert.Contains(t, buf.String(), `"service":"order"`)
+}
+
+func TestLogRecordsIncludeTraceID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(contextHandler{slog.NewJSONHandler(&buf, nil)})
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	logger.InfoContext(ctx, "order created")
+
+	assert.Contains(t, buf.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+}
+
+func TestClientInterceptorForwardsRequestID(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "abc")
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		assert.Equal(t, []string{"abc"}, md.Get(requestIDMetadata))
+		return nil
+	}
+
+	err := UnaryClientInterceptor()(ctx, "/ckeeper.menu.v1.MenuService/GetDish", nil, nil, nil, invoker)
+	assert.NoError(t, err)
+}
+
+func TestServerInterceptorReadsRequestID(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDMetadata, "abc"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/ckeeper.menu.v1.MenuService/GetDish"}
+
+	resp, err := UnaryServerInterceptor()(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return RequestID(ctx), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", resp)
+}
@@ -0,0 +1,40 @@
+// Команда menu запускает сервис меню: HTTP API и gRPC API с настройкой из переменных окружения
+package main
+
+import (
+	"c_keeper_go/logging"
+	"c_keeper_go/server"
+	"c_keeper_go/tracing"
+	"context"
+	"log/slog"
+	"menu"
+	"net/http"
+)
+
+func main() {
+	logging.Setup("menu")
+	shutdownTracing, err := tracing.Setup("menu")
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	config := menu.ConfigFromEnv()
+	if err := menu.Setup(config); err != nil {
+		logging.Fatal("Failed to set up service", "error", err)
+	}
+
+	// gRPC API работает рядом с REST на отдельном порту; по SIGTERM оба сервера
+	// перестают принимать подключения и дорабатывают начатые запросы
+	srv := server.Server{
+		HTTP:            &http.Server{Addr: config.HTTPAddr, Handler: menu.NewRouter()},
+		GRPC:            menu.NewGRPCServer(),
+		GRPCAddr:        config.GRPCAddr,
+		ShutdownTimeout: config.ShutdownTimeout,
+		OnShutdown:      menu.Shutdown,
+	}
+	if err := srv.Run(); err != nil {
+		slog.Error("Server stopped with error", "error", err)
+	}
+	menu.Close()
+}
@@ -0,0 +1,85 @@
+package menu
+
+import (
+	"c_keeper_go/apierr"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Сообщения сервиса: ошибки и ответы об успешных операциях; общие (internal_error, validation_failed, field_*) заданы в apierr
+var messages = apierr.Messages{
+	"ru": {
+		"invalid_id":              "Некорректный ID",
+		"dish_not_found":          "Блюдо не найдено",
+		"dish_not_found_id":       "Блюдо %v не найдено",
+		"dish_not_deleted":        "Блюдо не удалено",
+		"dish_modified":           "Блюдо было изменено, обновите данные и повторите",
+		"dish_has_open_orders":    "У блюда есть открытые заказы: %v",
+		"hard_delete_forbidden":   "Полное удаление доступно только менеджерам",
+		"orders_unavailable":      "Не удалось проверить открытые заказы",
+		"not_enough_stock":        "Недостаточно порций, в наличии: %v",
+		"modifier_not_found":      "Модификатор не найден",
+		"nothing_to_update":       "Нет данных для обновления",
+		"ingredient_not_found":    "Ингредиент не найден",
+		"ingredient_exists":       "Ингредиент «%v» уже есть",
+		"not_enough_ingredient":   "Недостаточно ингредиента, на складе: %v",
+		"price_not_found":         "Изменение цены не найдено",
+		"price_already_effective": "Цена уже вступила в силу, отменить её нельзя",
+
+		"field_removal_price": "удаление ингредиента не может менять цену",
+		"field_positive":      "должно быть больше нуля",
+		"field_from_recipe":   "считается по рецепту и не задаётся вручную",
+		"field_before_start":  "не может быть раньше начала",
+		"field_same_as_start": "не может совпадать с началом",
+		"field_in_future":     "должно быть в будущем",
+
+		"dish_deleted":             "Блюдо удалено",
+		"dish_deleted_permanently": "Блюдо удалено окончательно",
+		"modifier_deleted":         "Модификатор удалён",
+		"price_cancelled":          "Запланированная цена отменена",
+	},
+	"en": {
+		"invalid_id":              "Invalid ID",
+		"dish_not_found":          "Dish not found",
+		"dish_not_found_id":       "Dish %v not found",
+		"dish_not_deleted":        "Dish is not deleted",
+		"dish_modified":           "Dish was modified, reload and retry",
+		"dish_has_open_orders":    "Dish has open orders: %v",
+		"hard_delete_forbidden":   "Hard delete is allowed for managers only",
+		"orders_unavailable":      "Cannot check open orders",
+		"not_enough_stock":        "Not enough stock, available: %v",
+		"modifier_not_found":      "Modifier not found",
+		"nothing_to_update":       "Nothing to update",
+		"ingredient_not_found":    "Ingredient not found",
+		"ingredient_exists":       "Ingredient %q already exists",
+		"not_enough_ingredient":   "Not enough ingredient in stock: %v",
+		"price_not_found":         "Price change not found",
+		"price_already_effective": "Price is already in effect and cannot be cancelled",
+
+		"field_removal_price": "ingredient removal cannot change price",
+		"field_positive":      "must be greater than zero",
+		"field_from_recipe":   "is derived from the recipe and cannot be set",
+		"field_before_start":  "cannot be before the start",
+		"field_same_as_start": "cannot equal the start",
+		"field_in_future":     "must be in the future",
+
+		"dish_deleted":             "Dish deleted successfully",
+		"dish_deleted_permanently": "Dish permanently deleted",
+		"modifier_deleted":         "Modifier deleted successfully",
+		"price_cancelled":          "Scheduled price cancelled",
+	},
+}
+
+// Ошибка базы данных: отсутствие записи — 404 с кодом notFoundCode, остальное — внутренняя ошибка
+func dbError(err error, notFoundCode string, args ...interface{}) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apierr.NotFound(notFoundCode, args...)
+	}
+	return apierr.Internal(err)
+}
+
+// Сообщение об успешной операции на языке запроса (Accept-Language)
+func successMessage(c *gin.Context, code string) string {
+	return messages.Localize(apierr.RequestLanguage(c), code)
+}
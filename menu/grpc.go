@@ -0,0 +1,102 @@
+package menu
+
+import (
+	"c_keeper_go/apierr"
+	"c_keeper_go/logging"
+	menuv1 "c_keeper_go/proto/menu/v1"
+	"c_keeper_go/tracing"
+	"context"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"time"
+)
+
+// gRPC-сервер меню; бизнес-логика общая с обработчиками gin
+type menuServer struct {
+	menuv1.UnimplementedMenuServiceServer
+}
+
+func (menuServer) ListDishes(ctx context.Context, req *menuv1.ListDishesRequest) (*menuv1.ListDishesResponse, error) {
+	query := db.WithContext(ctx)
+	if req.GetIncludeDeleted() {
+		query = query.Unscoped()
+	}
+	menu, err := listDishes(query)
+	if err != nil {
+		return nil, err
+	}
+	dishes := make([]*menuv1.Dish, 0, len(menu))
+	for _, dish := range menu {
+		dishes = append(dishes, dishToProto(dish))
+	}
+	return &menuv1.ListDishesResponse{Dishes: dishes}, nil
+}
+
+func (menuServer) GetDish(ctx context.Context, req *menuv1.GetDishRequest) (*menuv1.Dish, error) {
+	dish, err := findDish(db.WithContext(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return dishToProto(dish), nil
+}
+
+func (menuServer) AdjustStock(ctx context.Context, req *menuv1.AdjustStockRequest) (*menuv1.Dish, error) {
+	dish, err := changeStock(ctx, int(req.GetId()), int(req.GetDelta()), req.GetConsumed())
+	if err != nil {
+		return nil, err
+	}
+	return dishToProto(dish), nil
+}
+
+func (menuServer) ConsumeStock(ctx context.Context, req *menuv1.ConsumeStockRequest) (*menuv1.Dish, error) {
+	dish, err := consumeStock(ctx, int(req.GetId()), int(req.GetQuantity()))
+	if err != nil {
+		return nil, err
+	}
+	return dishToProto(dish), nil
+}
+
+// Блюдо в формате protobuf
+func dishToProto(dish Menu) *menuv1.Dish {
+	modifiers := make([]*menuv1.Modifier, 0, len(dish.Modifiers))
+	for _, modifier := range dish.Modifiers {
+		modifiers = append(modifiers, &menuv1.Modifier{
+			Id:         uint32(modifier.ID),
+			MenuId:     uint32(modifier.MenuID),
+			Name:       modifier.Name,
+			Type:       modifier.Type,
+			PriceDelta: modifier.PriceDelta,
+		})
+	}
+	return &menuv1.Dish{
+		Id:                uint32(dish.ID),
+		Name:              dish.Name,
+		Price:             dish.Price,
+		Description:       dish.Description,
+		CategoryId:        uint32(dish.CategoryID),
+		AvailableQuantity: int32(dish.AvailableQuantity),
+		Category:          &menuv1.Category{Id: uint32(dish.Category.ID), Name: dish.Category.Name},
+		Modifiers:         modifiers,
+		Version:           int32(dish.Version),
+		OutsideSchedule:   !dish.availableAt(time.Now()),
+	}
+}
+
+// Состояние по протоколу grpc.health.v1; по нему сервис заказов проверяет свою готовность
+var grpcHealth = grpchealth.NewServer()
+
+// NewGRPCServer — gRPC-сервер с тем же каталогом ошибок, что и REST
+func NewGRPCServer() *grpc.Server {
+	server := grpc.NewServer(
+		tracing.ServerHandler(),
+		grpc.ChainUnaryInterceptor(
+			logging.UnaryServerInterceptor(),
+			apierr.UnaryServerInterceptor(messages),
+		),
+	)
+	menuv1.RegisterMenuServiceServer(server, menuServer{})
+	grpcHealth.SetServingStatus(menuv1.MenuService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, grpcHealth)
+	return server
+}
@@ -0,0 +1,386 @@
+package menu
+
+import (
+	"c_keeper_go/apierr"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ингредиент на складе
+type Ingredient struct {
+	ID       uint    `gorm:"primaryKey" json:"id"`
+	Name     string  `gorm:"uniqueIndex" json:"name"`
+	Unit     string  `json:"unit"`     // g, ml, pcs
+	Stock    float64 `json:"stock"`    // остаток на складе
+	Reserved float64 `json:"reserved"` // зарезервировано под принятые, но ещё не приготовленные заказы
+}
+
+// Единицы измерения ингредиентов
+const (
+	unitGram   = "g"
+	unitMl     = "ml"
+	unitPieces = "pcs"
+)
+
+var validUnits = map[string]bool{unitGram: true, unitMl: true, unitPieces: true}
+
+// Строка рецепта: сколько ингредиента уходит на одну порцию блюда
+type RecipeItem struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	MenuID       uint       `gorm:"uniqueIndex:idx_recipe_item" json:"menu_id"`
+	IngredientID uint       `gorm:"uniqueIndex:idx_recipe_item" json:"ingredient_id"`
+	Quantity     float64    `json:"quantity"` // в единицах ингредиента
+	Ingredient   Ingredient `json:"ingredient"`
+}
+
+func (i Ingredient) validate() apierr.FieldErrors {
+	errs := apierr.FieldErrors{}
+	validateString(errs, "name", i.Name, true, 255)
+	if !validUnits[i.Unit] {
+		errs.Add("unit", "one_of", strings.Join([]string{unitGram, unitMl, unitPieces}, ", "))
+	}
+	if i.Stock < 0 {
+		errs.Add("stock", "min", 0)
+	}
+	return errs
+}
+
+// Пересчёт доступных порций блюд, в рецептах которых есть эти ингредиенты:
+// минимум по ингредиентам от (остаток - резерв) / расход на порцию.
+// Версия растёт только у блюд, у которых число порций изменилось
+func refreshPortions(tx *gorm.DB, ingredientIDs []uint) error {
+	if len(ingredientIDs) == 0 {
+		return nil
+	}
+	return tx.Exec(`UPDATE menus SET available_quantity = portions.available, version = menus.version + 1
+		FROM (
+			SELECT r.menu_id, MIN(FLOOR(GREATEST(i.stock - i.reserved, 0) / r.quantity + 1e-9))::int AS available
+			FROM recipe_items r JOIN ingredients i ON i.id = r.ingredient_id
+			WHERE r.menu_id IN (SELECT menu_id FROM recipe_items WHERE ingredient_id IN ?)
+			GROUP BY r.menu_id
+		) portions
+		WHERE menus.id = portions.menu_id AND menus.available_quantity <> portions.available`, ingredientIDs).Error
+}
+
+// Есть ли у блюда рецепт
+func dishHasRecipe(tx *gorm.DB, menuID uint) (bool, error) {
+	var count int64
+	err := tx.Model(&RecipeItem{}).Where("menu_id = ?", menuID).Count(&count).Error
+	return count > 0, err
+}
+
+// ID ингредиентов рецепта по возрастанию: строки блокируются в одном порядке и не дают взаимоблокировок
+func recipeIngredientIDs(recipe []RecipeItem) []uint {
+	ids := make([]uint, len(recipe))
+	for i, item := range recipe {
+		ids[i] = item.IngredientID
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Расход ингредиентов на quantity порций по ID ингредиента
+func recipeAmounts(recipe []RecipeItem, quantity int) map[uint]float64 {
+	amounts := make(map[uint]float64, len(recipe))
+	for _, item := range recipe {
+		amounts[item.IngredientID] = item.Quantity * float64(quantity)
+	}
+	return amounts
+}
+
+// Резерв ингредиентов блюда с рецептом: отрицательный delta резервирует, положительный снимает резерв.
+// consumed — порции уже списаны, и delta меняет сам остаток ингредиентов
+func changeIngredientStock(ctx context.Context, dish Menu, delta int, consumed bool) error {
+	amounts := recipeAmounts(dish.Recipe, -delta)
+	ids := recipeIngredientIDs(dish.Recipe)
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			amount := amounts[id]
+			query := tx.Model(&Ingredient{}).Where("id = ?", id)
+			var update interface{}
+			if consumed {
+				// Порции уже списаны: возврат или повторное списание меняет сам остаток
+				if amount > 0 {
+					query = query.Where("stock >= ?", amount)
+				}
+				update = map[string]interface{}{"stock": gorm.Expr("stock - ?", amount)}
+			} else {
+				if amount > 0 {
+					query = query.Where("stock - reserved >= ?", amount)
+				}
+				update = map[string]interface{}{"reserved": gorm.Expr("GREATEST(reserved + ?, 0)", amount)}
+			}
+			result := query.Updates(update)
+			if result.Error != nil {
+				return apierr.Internal(result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return apierr.Conflict("not_enough_stock", dish.AvailableQuantity)
+			}
+		}
+		if err := refreshPortions(tx, ids); err != nil {
+			return apierr.Internal(err)
+		}
+		return nil
+	})
+}
+
+// Списание порций, которые начала готовить кухня; отрицательный quantity отменяет списание.
+// У блюд без рецепта порции уже списаны при резерве
+func consumeStock(ctx context.Context, id int, quantity int) (Menu, error) {
+	// Блюдо могли удалить из меню, пока заказ ждал кухню
+	dish, err := findDish(db.WithContext(ctx).Unscoped(), id)
+	if err != nil || len(dish.Recipe) == 0 {
+		return dish, err
+	}
+
+	amounts := recipeAmounts(dish.Recipe, quantity)
+	ids := recipeIngredientIDs(dish.Recipe)
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ingredientID := range ids {
+			amount := amounts[ingredientID]
+			// Списываемые порции должны быть в резерве: расхождение не скрывается, а прерывает списание
+			query := tx.Model(&Ingredient{}).Where("id = ?", ingredientID)
+			if amount > 0 {
+				query = query.Where("stock >= ? AND reserved >= ?", amount, amount)
+			}
+			result := query.Updates(map[string]interface{}{
+				"stock":    gorm.Expr("stock - ?", amount),
+				"reserved": gorm.Expr("reserved - ?", amount),
+			})
+			if result.Error != nil {
+				return apierr.Internal(result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return apierr.Conflict("not_enough_stock", dish.AvailableQuantity)
+			}
+		}
+		if err := refreshPortions(tx, ids); err != nil {
+			return apierr.Internal(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return dish, err
+	}
+	syncStockAlerts(ctx)
+	return findDish(db.WithContext(ctx).Unscoped(), id)
+}
+
+// Список ингредиентов (GET /ingredients)
+func getIngredients(c *gin.Context) {
+	var ingredients []Ingredient
+	if err := requestDB(c).Order("name").Find(&ingredients).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, ingredients)
+}
+
+// Добавление ингредиента (POST /ingredients)
+func addIngredient(c *gin.Context) {
+	var ingredient Ingredient
+	if err := c.ShouldBindJSON(&ingredient); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	ingredient.Name = strings.TrimSpace(ingredient.Name)
+	if errs := ingredient.validate(); len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	var existing int64
+	if err := requestDB(c).Model(&Ingredient{}).Where("name = ?", ingredient.Name).Count(&existing).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	if existing > 0 {
+		apierr.Abort(c, apierr.Conflict("ingredient_exists", ingredient.Name))
+		return
+	}
+
+	ingredient.ID = 0
+	ingredient.Reserved = 0
+	if err := requestDB(c).Create(&ingredient).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusCreated, ingredient)
+}
+
+// Поступление (delta > 0) или списание (delta < 0) ингредиента на складе (POST /ingredients/:id/stock).
+// Доступные порции блюд с этим ингредиентом пересчитываются
+func adjustIngredientStock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var req struct {
+		Delta float64 `json:"delta"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+
+	var ingredient Ingredient
+	err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&ingredient, id).Error; err != nil {
+			return dbError(err, "ingredient_not_found")
+		}
+		result := tx.Model(&Ingredient{}).
+			Where("id = ? AND stock + ? >= 0", id, req.Delta).
+			Update("stock", gorm.Expr("stock + ?", req.Delta))
+		if result.Error != nil {
+			return apierr.Internal(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return apierr.Conflict("not_enough_ingredient", ingredient.Stock)
+		}
+		if err := refreshPortions(tx, []uint{ingredient.ID}); err != nil {
+			return apierr.Internal(err)
+		}
+		return tx.First(&ingredient, id).Error
+	})
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	syncStockAlerts(c.Request.Context())
+	c.JSON(http.StatusOK, ingredient)
+}
+
+// Рецепт блюда (GET /menu/:id/recipe)
+func getRecipe(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var dish Menu
+	if err := requestDB(c).First(&dish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
+		return
+	}
+
+	var recipe []RecipeItem
+	if err := requestDB(c).Preload("Ingredient").Where("menu_id = ?", id).Order("id").Find(&recipe).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, recipe)
+}
+
+// Замена рецепта блюда (PUT /menu/:id/recipe). С рецептом доступные порции считаются
+// по остаткам ингредиентов; пустой рецепт возвращает ручной учёт порций.
+// Открытые заказы зарезервировали и списывают порции по прежнему рецепту,
+// поэтому, пока они есть, рецепт не меняется
+func setRecipe(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var items []RecipeItem
+	if err := c.ShouldBindJSON(&items); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+
+	var dish Menu
+	if err := requestDB(c).First(&dish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
+		return
+	}
+
+	errs := apierr.FieldErrors{}
+	seen := map[uint]bool{}
+	ids := make([]uint, 0, len(items))
+	for i, item := range items {
+		field := fmt.Sprintf("[%d].", i)
+		if item.Quantity <= 0 {
+			errs.Add(field+"quantity", "positive", nil)
+		}
+		if seen[item.IngredientID] {
+			errs.Add(field+"ingredient_id", "duplicate", nil)
+		}
+		seen[item.IngredientID] = true
+		ids = append(ids, item.IngredientID)
+	}
+
+	var known []uint
+	if err := requestDB(c).Model(&Ingredient{}).Where("id IN ?", append(ids, 0)).Pluck("id", &known).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	exists := map[uint]bool{}
+	for _, ingredientID := range known {
+		exists[ingredientID] = true
+	}
+	for i, item := range items {
+		if !exists[item.IngredientID] {
+			errs.Add(fmt.Sprintf("[%d].ingredient_id", i), "unknown", nil)
+		}
+	}
+	if len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	// Проверка и замена в одной транзакции: блокировка блюда не даёт принять заказ,
+	// пока рецепт меняется, и резерв не разойдётся с новым рецептом
+	err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dish, dish.ID).Error; err != nil {
+			return dbError(err, "dish_not_found")
+		}
+		openOrders, err := countOpenOrders(c.Request.Context(), int(dish.ID))
+		if err != nil {
+			return apierr.Upstream("orders_unavailable", err)
+		}
+		if openOrders > 0 {
+			return apierr.Conflict("dish_has_open_orders", openOrders)
+		}
+
+		if err := tx.Where("menu_id = ?", dish.ID).Delete(&RecipeItem{}).Error; err != nil {
+			return apierr.Internal(err)
+		}
+		for i := range items {
+			items[i].ID = 0
+			items[i].MenuID = dish.ID
+			items[i].Ingredient = Ingredient{}
+		}
+		if len(items) > 0 {
+			if err := tx.Omit("Ingredient").Create(&items).Error; err != nil {
+				return apierr.Internal(err)
+			}
+		}
+		if err := refreshPortions(tx, ids); err != nil {
+			return apierr.Internal(err)
+		}
+		return nil
+	})
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	syncStockAlerts(c.Request.Context())
+
+	var recipe []RecipeItem
+	if err := requestDB(c).Preload("Ingredient").Where("menu_id = ?", dish.ID).Order("id").Find(&recipe).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, recipe)
+}
@@ -1,14 +1,23 @@
-package main
+package menu
 
 import (
+	"c_keeper_go/apierr"
+	"c_keeper_go/health"
+	"c_keeper_go/logging"
+	"c_keeper_go/metrics"
+	"c_keeper_go/tracing"
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
+	"time"
 )
 
 // Модели для таблиц
@@ -18,70 +27,265 @@ type Category struct {
 }
 
 type Menu struct {
-	ID                uint     `gorm:"primaryKey" json:"id"`
-	Name              string   `json:"name"`
-	Price             float64  `json:"price"`
-	Description       string   `json:"description"`
-	CategoryID        uint     `json:"category_id"`
-	AvailableQuantity int      `json:"available_quantity"`
-	Category          Category `gorm:"foreignKey:CategoryID;references:ID" json:"category"` // связь с таблицей categories
+	ID                uint             `gorm:"primaryKey" json:"id"`
+	Name              string           `json:"name"`
+	Price             float64          `json:"price"`
+	Description       string           `json:"description"`
+	CategoryID        uint             `json:"category_id"`
+	AvailableQuantity int              `json:"available_quantity"`
+	Category          Category         `gorm:"foreignKey:CategoryID;references:ID" json:"category"` // связь с таблицей categories
+	DeletedAt         gorm.DeletedAt   `gorm:"index" json:"deleted_at"`                             // мягкое удаление
+	Modifiers         []Modifier       `gorm:"foreignKey:MenuID" json:"modifiers"`                  // размеры, добавки, убираемые ингредиенты
+	Version           int              `gorm:"not null;default:1" json:"version"`                   // для оптимистичной блокировки
+	Recipe            []RecipeItem     `gorm:"foreignKey:MenuID" json:"recipe"`                     // с рецептом AvailableQuantity считается по ингредиентам
+	LowStockThreshold int              `gorm:"not null;default:0" json:"low_stock_threshold"`       // при остатке не выше порога блюдо попадает в стоп-лист как заканчивающееся
+	StockStatus       string           `gorm:"-" json:"stock_status"`                               // in_stock, low или sold_out
+	StockAlert        string           `gorm:"not null;default:in_stock" json:"-"`                  // уровень из последнего уведомления
+	SeasonStart       *string          `gorm:"size:10" json:"season_start"`                         // сезонное блюдо: первый день продажи, YYYY-MM-DD
+	SeasonEnd         *string          `gorm:"size:10" json:"season_end"`                           // последний день продажи
+	Schedule          []ScheduleWindow `gorm:"foreignKey:MenuID" json:"schedule"`                   // окна доступности; пусто — весь день
 }
 
 // Глобальная переменная для работы с базой данных
 var db *gorm.DB
 
-func initDatabase() {
+// Адрес сервиса заказов, нужен для проверки открытых заказов перед полным удалением блюда
+var orderServiceURL string
+
+// Проверки готовности (/readyz); набор зависимостей задаёт Setup
+var readiness = health.New()
+
+// HTTP-клиент сервиса заказов; передаёт контекст трейса в заголовке traceparent
+var orderClient = &http.Client{Transport: tracing.Transport(nil)}
+
+// Значение переменной окружения или значение по умолчанию
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Config — настройки запуска сервиса
+type Config struct {
+	DatabaseDSN     string
+	OrderServiceURL string
+	HTTPAddr        string
+	GRPCAddr        string
+	ShutdownTimeout time.Duration // сколько ждать начатые запросы при остановке
+}
+
+// ConfigFromEnv читает настройки из переменных окружения, как в Docker Compose
+func ConfigFromEnv() Config {
+	return Config{
+		DatabaseDSN:     getEnv("DATABASE_DSN", "host=localhost user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"),
+		OrderServiceURL: getEnv("ORDER_SERVICE_URL", "http://localhost:5004"),
+		HTTPAddr:        ":" + getEnv("MENU_HTTP_PORT", "5003"),
+		GRPCAddr:        ":" + getEnv("MENU_GRPC_PORT", "6003"),
+		ShutdownTimeout: time.Duration(getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 15)) * time.Second,
+	}
+}
+
+// Setup подключает сервис к базе и сервису заказов; вызывается до NewRouter и NewGRPCServer
+func Setup(config Config) error {
+	if err := openDatabase(config.DatabaseDSN); err != nil {
+		return err
+	}
+	orderServiceURL = config.OrderServiceURL
+	readiness = health.New()
+	readiness.Add("postgres", health.DB(db))
+	return nil
+}
+
+// Shutdown переводит сервис в «не готов» перед остановкой серверов
+func Shutdown() {
+	readiness.Shutdown()
+	grpcHealth.Shutdown()
+}
+
+// Close закрывает соединения с базой после остановки серверов
+func Close() {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+func openDatabase(dsn string) error {
 	var err error
-	dsn := "host=localhost user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"
 	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	if err := configurePool(db); err != nil {
+		return fmt.Errorf("configure connection pool: %w", err)
+	}
+	if err := metrics.InstrumentGORM(db); err != nil {
+		return fmt.Errorf("instrument database: %w", err)
+	}
+	if err := tracing.InstrumentGORM(db); err != nil {
+		return fmt.Errorf("enable database tracing: %w", err)
 	}
 
 	// Автоматическая миграция схемы базы данных
-	err = db.AutoMigrate(&Category{}, &Menu{})
+	err = db.AutoMigrate(&Category{}, &Menu{}, &Modifier{}, &Ingredient{}, &RecipeItem{}, &ScheduleWindow{}, &PriceChange{})
 	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		return fmt.Errorf("migrate database: %w", err)
+	}
+	if err := backfillPriceHistory(db); err != nil {
+		return fmt.Errorf("backfill price history: %w", err)
 	}
-	fmt.Println("Database connected and migrated successfully")
+	slog.Info("Database connected and migrated successfully")
+	return nil
 }
 
-func main() {
-	initDatabase()
+// Размер пула соединений с базой (DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME_MINUTES)
+func configurePool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(getEnvInt("DB_MAX_OPEN_CONNS", 25))
+	sqlDB.SetMaxIdleConns(getEnvInt("DB_MAX_IDLE_CONNS", 10))
+	sqlDB.SetConnMaxLifetime(time.Duration(getEnvInt("DB_CONN_MAX_LIFETIME_MINUTES", 30)) * time.Minute)
+	return nil
+}
 
-	r := gin.Default()
+// NewRouter — HTTP API сервиса со всеми маршрутами
+func NewRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(tracing.Middleware("menu"))
+	r.Use(logging.Middleware())
+	r.Use(metrics.Middleware())
 	r.Use(cors.Default())
+	r.Use(apierr.Middleware(messages))
 
 	// CRUD-операции
 	r.GET("/menu", getMenu)
+	r.GET("/menu/stoplist", getStopList)
 	r.GET("/menu/:id", getDishByID) // Добавлен эндпоинт для получения блюда по ID
 	r.POST("/menu", addDish)
 	r.DELETE("/menu/:id", deleteDish)
 	r.PUT("/menu/:id", updateDish)
+	r.PATCH("/menu/:id", patchDish)
+	r.PATCH("/menu", patchDishes)
+	r.POST("/menu/:id/restore", restoreDish)
+	r.POST("/menu/:id/stock", adjustStock)
+	r.GET("/menu/:id/recipe", getRecipe)
+	r.PUT("/menu/:id/recipe", setRecipe)
+	r.GET("/menu/:id/prices", getPrices)
+	r.POST("/menu/:id/prices", schedulePrice)
+	r.DELETE("/menu/:id/prices/:priceId", cancelScheduledPrice)
+	r.GET("/menu/:id/schedule", getSchedule)
+	r.PUT("/menu/:id/schedule", setSchedule)
+	r.GET("/ingredients", getIngredients)
+	r.POST("/ingredients", addIngredient)
+	r.POST("/ingredients/:id/stock", adjustIngredientStock)
+	r.GET("/menu/:id/modifiers", getModifiers)
+	r.POST("/menu/:id/modifiers", addModifier)
+	r.DELETE("/menu/:id/modifiers/:modifierId", deleteModifier)
+
+	r.GET("/openapi.json", getOpenAPISpec)
+	r.GET("/metrics", metrics.Handler())
+	r.GET("/healthz", readiness.Liveness)
+	r.GET("/readyz", readiness.Readiness)
+	return r
+}
+
+// Менеджер определяется по заголовку X-User-Role
+func isManager(c *gin.Context) bool {
+	return c.GetHeader("X-User-Role") == "manager"
+}
+
+// База в контексте запроса: запросы к ней попадают в трейс запроса
+func requestDB(c *gin.Context) *gorm.DB {
+	return db.WithContext(c.Request.Context())
+}
 
-	if err := r.Run(":5003"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+// Запрос с учётом удалённых блюд (?include_deleted=true, только для менеджеров)
+func scopedDB(c *gin.Context) *gorm.DB {
+	if c.Query("include_deleted") == "true" && isManager(c) {
+		return requestDB(c).Unscoped()
 	}
+	return requestDB(c)
 }
 
-// Получение всего меню
-func getMenu(c *gin.Context) {
+// Все блюда с категориями, модификаторами, рецептами и расписанием; цены — действующие на этот момент
+func listDishes(query *gorm.DB) ([]Menu, error) {
 	var menu []Menu
-	if err := db.Preload("Category").Find(&menu).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := query.Preload("Category").Preload("Modifiers").Preload("Recipe.Ingredient").Preload("Schedule").Find(&menu).Error; err != nil {
+		return nil, apierr.Internal(err)
+	}
+	if err := resolvePrices(db.WithContext(query.Statement.Context), menu); err != nil {
+		return nil, apierr.Internal(err)
+	}
+	return menu, nil
+}
+
+// Блюдо с категорией, модификаторами, рецептом и расписанием; цена — действующая на этот момент
+func findDish(query *gorm.DB, id interface{}) (Menu, error) {
+	var dish Menu
+	if err := query.Preload("Category").Preload("Modifiers").Preload("Recipe.Ingredient").Preload("Schedule").First(&dish, id).Error; err != nil {
+		return dish, dbError(err, "dish_not_found")
+	}
+	dishes := []Menu{dish}
+	if err := resolvePrices(db.WithContext(query.Statement.Context), dishes); err != nil {
+		return dish, apierr.Internal(err)
+	}
+	return dishes[0], nil
+}
+
+// Получение всего меню. Закончившиеся блюда гостям не показываются,
+// менеджер видит их с stock_status=sold_out. ?at=<RFC 3339> оставляет блюда, которые можно заказать в этот момент
+func getMenu(c *gin.Context) {
+	var at time.Time
+	if value := c.Query("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			errs := apierr.FieldErrors{}
+			errs.Add("at", "invalid_type", nil)
+			apierr.Abort(c, apierr.Validation(errs))
+			return
+		}
+		at = parsed
+	}
+
+	query := scopedDB(c)
+	if !isManager(c) {
+		query = query.Where("available_quantity > 0")
+	}
+	menu, err := listDishes(query)
+	if err != nil {
+		apierr.Abort(c, err)
 		return
 	}
+	if !at.IsZero() {
+		menu = filterAvailable(menu, at)
+	}
 	c.JSON(http.StatusOK, menu)
 }
 
 // Получение блюда по ID
 func getDishByID(c *gin.Context) {
-	id := c.Param("id")
-	var dish Menu
-	if err := db.Preload("Category").First(&dish, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Dish not found"})
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
 		return
 	}
+	dish, err := findDish(scopedDB(c), id)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.Header("ETag", dishETag(dish))
 	c.JSON(http.StatusOK, dish)
 }
 
@@ -89,47 +293,153 @@ func getDishByID(c *gin.Context) {
 func addDish(c *gin.Context) {
 	var dish Menu
 	if err := c.ShouldBindJSON(&dish); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
 		return
 	}
-	if err := db.Create(&dish).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if errs := dish.validate(); len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
 		return
 	}
+	// Рецепт и расписание задаются отдельно через PUT /menu/:id/recipe и /menu/:id/schedule
+	dish.Recipe = nil
+	dish.SeasonStart, dish.SeasonEnd, dish.Schedule = nil, nil, nil
+	if err := createDish(requestDB(c), &dish); err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	syncStockAlerts(c.Request.Context())
 	c.JSON(http.StatusCreated, dish)
 }
 
-// Удаление блюда из меню
+// Количество открытых заказов на блюдо по данным сервиса заказов
+func countOpenOrders(ctx context.Context, menuID int) (int, error) {
+	url := fmt.Sprintf("%s/orders?menu_id=%d&open=true", orderServiceURL, menuID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	logging.SetRequestHeader(req)
+	resp, err := orderClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка соединения с order: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("order вернул статус: %d", resp.StatusCode)
+	}
+
+	var orders []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&orders); err != nil {
+		return 0, fmt.Errorf("ошибка декодирования ответа: %v", err)
+	}
+	return len(orders), nil
+}
+
+// Удаление блюда из меню (по умолчанию мягкое, ?hard=true — полное, только для менеджеров)
 func deleteDish(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	hard := c.Query("hard") == "true"
+	if hard && !isManager(c) {
+		apierr.Abort(c, apierr.Forbidden("hard_delete_forbidden"))
+		return
+	}
+
+	// Полностью удалить можно и уже мягко удалённое блюдо
+	query := requestDB(c)
+	if hard {
+		query = query.Unscoped()
+	}
+
+	var dish Menu
+	if err := query.First(&dish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
+		return
+	}
+
+	if !hard {
+		if err := requestDB(c).Delete(&dish).Error; err != nil {
+			apierr.Abort(c, apierr.Internal(err))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": successMessage(c, "dish_deleted")})
+		return
+	}
+
+	openOrders, err := countOpenOrders(c.Request.Context(), id)
+	if err != nil {
+		apierr.Abort(c, apierr.Upstream("orders_unavailable", err))
+		return
+	}
+	if openOrders > 0 {
+		apierr.Abort(c, apierr.Conflict("dish_has_open_orders", openOrders))
+		return
+	}
+
+	if err := requestDB(c).Unscoped().Delete(&dish).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": successMessage(c, "dish_deleted_permanently")})
+}
+
+// Восстановление мягко удалённого блюда
+func restoreDish(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var dish Menu
+	if err := requestDB(c).Unscoped().First(&dish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
 		return
 	}
-	if err := db.Delete(&Menu{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !dish.DeletedAt.Valid {
+		apierr.Abort(c, apierr.Conflict("dish_not_deleted"))
+		return
+	}
+
+	if err := requestDB(c).Unscoped().Model(&dish).Update("deleted_at", nil).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Dish deleted successfully"})
+	syncStockAlerts(c.Request.Context())
+	dish, err = findDish(requestDB(c), dish.ID)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dish)
 }
 
 // Обновление блюда в меню
 func updateDish(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
 		return
 	}
 
 	var updatedDish Menu
 	if err := c.ShouldBindJSON(&updatedDish); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
 		return
 	}
 
 	var existingDish Menu
-	if err := db.First(&existingDish, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Dish not found"})
+	if err := requestDB(c).First(&existingDish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
+		return
+	}
+
+	if !checkIfMatch(c, existingDish) {
 		return
 	}
 
@@ -137,11 +447,96 @@ func updateDish(c *gin.Context) {
 	existingDish.Price = updatedDish.Price
 	existingDish.Description = updatedDish.Description
 	existingDish.CategoryID = updatedDish.CategoryID
-	existingDish.AvailableQuantity = updatedDish.AvailableQuantity
+	existingDish.LowStockThreshold = updatedDish.LowStockThreshold
+	// У блюда с рецептом порции считаются по ингредиентам, присланное значение игнорируется
+	hasRecipe, err := dishHasRecipe(requestDB(c), existingDish.ID)
+	if err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	if !hasRecipe {
+		existingDish.AvailableQuantity = updatedDish.AvailableQuantity
+	}
+
+	if errs := existingDish.validate(); len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
 
-	if err := db.Save(&existingDish).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	err = saveDishVersioned(requestDB(c), &existingDish, map[string]interface{}{
+		"name":                existingDish.Name,
+		"price":               existingDish.Price,
+		"description":         existingDish.Description,
+		"category_id":         existingDish.CategoryID,
+		"available_quantity":  existingDish.AvailableQuantity,
+		"low_stock_threshold": existingDish.LowStockThreshold,
+	})
+	if err != nil {
+		apierr.Abort(c, err)
 		return
 	}
+	syncStockAlerts(c.Request.Context())
+	c.Header("ETag", dishETag(existingDish))
 	c.JSON(http.StatusOK, existingDish)
 }
+
+// Изменение остатка блюда на delta порций (резерв под заказ или возврат при отмене);
+// у блюда с рецептом резервируются ингредиенты
+func adjustStock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var req struct {
+		Delta int `json:"delta"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+
+	dish, err := changeStock(c.Request.Context(), id, req.Delta, false)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dish)
+}
+
+// Атомарное изменение остатка; общая логика для REST и gRPC.
+// consumed — порции уже списаны кухней (см. consumeStock)
+func changeStock(ctx context.Context, id int, delta int, consumed bool) (Menu, error) {
+	// Удалённое блюдо могло остаться в открытых заказах: их отмена возвращает остаток
+	dish, err := findDish(db.WithContext(ctx).Unscoped(), id)
+	if err != nil {
+		return dish, err
+	}
+
+	if len(dish.Recipe) > 0 {
+		if err := changeIngredientStock(ctx, dish, delta, consumed); err != nil {
+			return dish, err
+		}
+		syncStockAlerts(ctx)
+		return findDish(db.WithContext(ctx).Unscoped(), id)
+	}
+
+	// Условие в WHERE не даёт уйти в минус при параллельных заказах.
+	// Версия растёт, чтобы PUT по старым данным не затёр резерв
+	result := db.WithContext(ctx).Unscoped().Model(&Menu{}).
+		Where("id = ? AND available_quantity + ? >= 0", id, delta).
+		Updates(map[string]interface{}{
+			"available_quantity": gorm.Expr("available_quantity + ?", delta),
+			"version":            gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return dish, apierr.Internal(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return dish, apierr.Conflict("not_enough_stock", dish.AvailableQuantity)
+	}
+
+	syncStockAlerts(ctx)
+	return findDish(db.WithContext(ctx).Unscoped(), id)
+}
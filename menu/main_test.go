@@ -1,25 +1,147 @@
-package main
+package menu
 
 import (
 	"bytes"
+	"c_keeper_go/apierr"
+	"c_keeper_go/health"
+	"c_keeper_go/metrics"
+	menuv1 "c_keeper_go/proto/menu/v1"
+	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
+	"strings"
 	"testing"
-
-	"github.com/stretchr/testify/assert"
+	"time"
 )
 
+// Маршрутизатор по спецификации openapi.json для проверки запросов и ответов в тестах
+var specRouter = func() routers.Router {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		panic("ошибка разбора openapi.json: " + err.Error())
+	}
+	// Тестовые запросы идут без хоста, поэтому адреса серверов не учитываем
+	doc.Servers = nil
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		panic("ошибка построения маршрутов openapi.json: " + err.Error())
+	}
+	return router
+}()
+
+// Проверка запроса по спецификации; тело запроса остаётся доступным для обработчика
+func validateRequest(t *testing.T, req *http.Request) {
+	t.Helper()
+	route, pathParams, err := specRouter.FindRoute(req)
+	if !assert.NoError(t, err, "маршрут отсутствует в openapi.json") {
+		return
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	assert.NoError(t, openapi3filter.ValidateRequest(context.Background(), input), "запрос не соответствует openapi.json")
+	req.Body = io.NopCloser(bytes.NewReader(body))
+}
+
+// Проверка ответа по спецификации
+func validateResponse(t *testing.T, req *http.Request, w *httptest.ResponseRecorder) {
+	t.Helper()
+	route, pathParams, err := specRouter.FindRoute(req)
+	if !assert.NoError(t, err, "маршрут отсутствует в openapi.json") {
+		return
+	}
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status:  w.Code,
+		Header:  w.Header(),
+		Body:    io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+		Options: &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	assert.NoError(t, openapi3filter.ValidateResponse(context.Background(), input), "ответ не соответствует openapi.json")
+}
+
+// Выполнение запроса с проверкой запроса и ответа по спецификации
+func serveWithSpec(t *testing.T, router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	validateRequest(t, req)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	validateResponse(t, req, w)
+	return w
+}
+
+// База для тестов: Postgres на localhost, как в make up
+func initDatabase() {
+	if err := openDatabase("host=localhost user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"); err != nil {
+		panic(err)
+	}
+}
+
 func setupRouter() *gin.Engine {
 	r := gin.Default()
+	r.Use(apierr.Middleware(messages))
 
 	// Роуты из main.go
 	r.GET("/menu", getMenu)
+	r.GET("/menu/stoplist", getStopList)
 	r.POST("/menu", addDish)
 	r.DELETE("/menu/:id", deleteDish)
 	r.PUT("/menu/:id", updateDish)
+	r.PATCH("/menu/:id", patchDish)
+	r.PATCH("/menu", patchDishes)
+	r.GET("/menu/:id", getDishByID)
+	r.POST("/menu/:id/restore", restoreDish)
+	r.POST("/menu/:id/stock", adjustStock)
+	r.GET("/menu/:id/recipe", getRecipe)
+	r.PUT("/menu/:id/recipe", setRecipe)
+	r.GET("/menu/:id/prices", getPrices)
+	r.POST("/menu/:id/prices", schedulePrice)
+	r.DELETE("/menu/:id/prices/:priceId", cancelScheduledPrice)
+	r.GET("/menu/:id/schedule", getSchedule)
+	r.PUT("/menu/:id/schedule", setSchedule)
+	r.GET("/ingredients", getIngredients)
+	r.POST("/ingredients", addIngredient)
+	r.POST("/ingredients/:id/stock", adjustIngredientStock)
+	r.GET("/menu/:id/modifiers", getModifiers)
+	r.POST("/menu/:id/modifiers", addModifier)
+	r.DELETE("/menu/:id/modifiers/:modifierId", deleteModifier)
+	r.GET("/openapi.json", getOpenAPISpec)
+	r.GET("/metrics", metrics.Handler())
+
+	readiness := health.New()
+	readiness.Add("postgres", health.DB(db))
+	r.GET("/healthz", readiness.Liveness)
+	r.GET("/readyz", readiness.Readiness)
 
 	return r
 }
@@ -32,8 +154,7 @@ func TestGetMenu(t *testing.T) {
 	db.Create(&Menu{Name: "Test Dish", Price: 10.5, Description: "Delicious", AvailableQuantity: 5, CategoryID: 1})
 
 	req, _ := http.NewRequest("GET", "/menu", nil)
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
+	w := serveWithSpec(t, router, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
@@ -52,8 +173,7 @@ func TestDeleteDish(t *testing.T) {
 	db.Create(&dish)
 
 	req, _ := http.NewRequest("DELETE", "/menu/"+strconv.Itoa(int(dish.ID)), nil)
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
+	w := serveWithSpec(t, router, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
@@ -73,8 +193,7 @@ func TestAddDish(t *testing.T) {
 
 	req, _ := http.NewRequest("POST", "/menu", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
+	w := serveWithSpec(t, router, req)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 
@@ -105,8 +224,7 @@ func TestUpdateDish(t *testing.T) {
 
 	req, _ := http.NewRequest("PUT", "/menu/"+strconv.Itoa(int(dish.ID)), bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, req)
+	w := serveWithSpec(t, router, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
@@ -118,3 +236,815 @@ func TestUpdateDish(t *testing.T) {
 	assert.Equal(t, updatedDish.Description, dishResponse.Description)
 	assert.Equal(t, updatedDish.AvailableQuantity, dishResponse.AvailableQuantity)
 }
+
+func TestSoftDeleteAndRestoreDish(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	dish := Menu{Name: "Soft Deleted", Price: 11.0, Description: "Soft", AvailableQuantity: 3, CategoryID: 1}
+	db.Create(&dish)
+	path := "/menu/" + strconv.Itoa(int(dish.ID))
+
+	req, _ := http.NewRequest("DELETE", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Удалённое блюдо не видно по умолчанию
+	req, _ = http.NewRequest("GET", path, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Менеджер видит его с include_deleted
+	req, _ = http.NewRequest("GET", path+"?include_deleted=true", nil)
+	req.Header.Set("X-User-Role", "manager")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("POST", path+"/restore", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", path, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// Сервис заказов, отвечающий на запрос открытых заказов списком orders
+func startOrderService(t *testing.T, orders string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(orders))
+	}))
+	previous := orderServiceURL
+	orderServiceURL = server.URL
+	t.Cleanup(func() {
+		orderServiceURL = previous
+		server.Close()
+	})
+}
+
+func TestHardDeleteDishWithOpenOrders(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	// Сервис заказов сообщает об одном открытом заказе
+	startOrderService(t, `[{"ID": 1, "status": "В процессе"}]`)
+
+	dish := Menu{Name: "Ordered Dish", Price: 9.0, Description: "Has orders", AvailableQuantity: 2, CategoryID: 1}
+	db.Create(&dish)
+
+	req, _ := http.NewRequest("DELETE", "/menu/"+strconv.Itoa(int(dish.ID))+"?hard=true", nil)
+	req.Header.Set("X-User-Role", "manager")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestAdjustStock(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	dish := Menu{Name: "Stocked", Price: 5.0, Description: "Stock", AvailableQuantity: 3, CategoryID: 1}
+	db.Create(&dish)
+	path := "/menu/" + strconv.Itoa(int(dish.ID)) + "/stock"
+
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString(`{"delta": -2}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var dishResponse Menu
+	json.Unmarshal(w.Body.Bytes(), &dishResponse)
+	assert.Equal(t, 1, dishResponse.AvailableQuantity)
+
+	// В минус уйти нельзя
+	req, _ = http.NewRequest("POST", path, bytes.NewBufferString(`{"delta": -2}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestAddModifier(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	dish := Menu{Name: "Борщ", Price: 120.5, Description: "С модификаторами", AvailableQuantity: 5, CategoryID: 1}
+	db.Create(&dish)
+	path := "/menu/" + strconv.Itoa(int(dish.ID)) + "/modifiers"
+
+	body, _ := json.Marshal(Modifier{Name: "Без сметаны", Type: "removal"})
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Неизвестный тип модификатора
+	body, _ = json.Marshal(Modifier{Name: "Что-то", Type: "unknown"})
+	req, _ = http.NewRequest("POST", path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("GET", "/menu/"+strconv.Itoa(int(dish.ID)), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var dishResponse Menu
+	json.Unmarshal(w.Body.Bytes(), &dishResponse)
+	assert.Len(t, dishResponse.Modifiers, 1)
+	assert.Equal(t, "Без сметаны", dishResponse.Modifiers[0].Name)
+}
+
+func TestUpdateDishStaleIfMatch(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	dish := Menu{Name: "Versioned", Price: 10.0, Description: "v1", AvailableQuantity: 5, CategoryID: 1}
+	db.Create(&dish)
+	path := "/menu/" + strconv.Itoa(int(dish.ID))
+
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	update := func(description string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(Menu{Name: "Versioned", Price: 10.0, Description: description, AvailableQuantity: 5, CategoryID: 1})
+		req, _ := http.NewRequest("PUT", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", etag)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Первый менеджер успевает сохранить, второй работает со старой версией
+	assert.Equal(t, http.StatusOK, update("first manager").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, update("second manager").Code)
+}
+
+func TestPatchDishKeepsOtherFields(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	dish := Menu{Name: "Patched", Price: 10.0, Description: "Before patch", AvailableQuantity: 7, CategoryID: 1}
+	db.Create(&dish)
+
+	req, _ := http.NewRequest("PATCH", "/menu/"+strconv.Itoa(int(dish.ID)), bytes.NewBufferString(`{"price": 12.5, "description": null}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := serveWithSpec(t, router, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var dishResponse Menu
+	json.Unmarshal(w.Body.Bytes(), &dishResponse)
+	assert.Equal(t, 12.5, dishResponse.Price)
+	assert.Equal(t, "", dishResponse.Description)
+	assert.Equal(t, 7, dishResponse.AvailableQuantity)
+	assert.Equal(t, uint(1), dishResponse.CategoryID)
+}
+
+func TestPatchDishValidation(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	dish := Menu{Name: "Invalid Patch", Price: 10.0, AvailableQuantity: 7, CategoryID: 1}
+	db.Create(&dish)
+
+	req, _ := http.NewRequest("PATCH", "/menu/"+strconv.Itoa(int(dish.ID)), bytes.NewBufferString(`{"price": -1, "name": ""}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "price")
+	assert.Contains(t, w.Body.String(), "name")
+}
+
+func TestBulkPatchDishesIsAtomic(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	first := Menu{Name: "Bulk 1", Price: 10.0, AvailableQuantity: 1, CategoryID: 1}
+	second := Menu{Name: "Bulk 2", Price: 20.0, AvailableQuantity: 1, CategoryID: 1}
+	db.Create(&first)
+	db.Create(&second)
+
+	// Второе блюдо не существует — первое тоже не должно измениться
+	body := fmt.Sprintf(`[{"id": %d, "price": 11}, {"id": 999999, "price": 5}]`, first.ID)
+	req, _ := http.NewRequest("PATCH", "/menu", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var reloaded Menu
+	db.First(&reloaded, first.ID)
+	assert.Equal(t, 10.0, reloaded.Price)
+
+	body = fmt.Sprintf(`[{"id": %d, "price": 11}, {"id": %d, "price": 21}]`, first.ID, second.ID)
+	req, _ = http.NewRequest("PATCH", "/menu", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	db.First(&reloaded, second.ID)
+	assert.Equal(t, 21.0, reloaded.Price)
+}
+
+func TestAddDishValidation(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	body, _ := json.Marshal(Menu{Name: "", Price: -1, AvailableQuantity: -5, CategoryID: 1})
+	req, _ := http.NewRequest("POST", "/menu", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	validateResponse(t, req, w)
+
+	var response struct {
+		Error apierr.Response `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "validation_failed", response.Error.Code)
+	assert.Equal(t, "обязательное поле", response.Error.Fields["name"])
+	assert.Equal(t, "должно быть не меньше 0", response.Error.Fields["price"])
+	assert.Contains(t, response.Error.Fields, "available_quantity")
+}
+
+func TestErrorLanguageFromAcceptLanguage(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	for lang, message := range map[string]string{"": "Блюдо не найдено", "en": "Dish not found", "ru-RU,ru;q=0.9": "Блюдо не найдено"} {
+		req, _ := http.NewRequest("GET", "/menu/999999", nil)
+		req.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			Error apierr.Response `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "dish_not_found", response.Error.Code)
+		assert.Equal(t, message, response.Error.Message)
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	assert.NoError(t, err)
+	assert.NoError(t, doc.Validate(context.Background()))
+
+	router := setupRouter()
+
+	// Каждый маршрут сервиса описан в спецификации
+	for _, route := range router.Routes() {
+		path := strings.NewReplacer(":id", "1", ":modifierId", "1").Replace(route.Path)
+		req, _ := http.NewRequest(route.Method, path, nil)
+		_, _, err := specRouter.FindRoute(req)
+		assert.NoError(t, err, "%s %s нет в openapi.json", route.Method, route.Path)
+	}
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, string(openAPISpec), w.Body.String())
+}
+
+// gRPC-клиент к серверу меню в памяти
+func startGRPC(t *testing.T) menuv1.MenuServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewGRPCServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return menuv1.NewMenuServiceClient(conn)
+}
+
+func TestGRPCAdjustStock(t *testing.T) {
+	initDatabase()
+	client := startGRPC(t)
+	ctx := context.Background()
+
+	dish := Menu{Name: "gRPC", Price: 5.0, Description: "Stock", AvailableQuantity: 3, CategoryID: 1}
+	db.Create(&dish)
+
+	resp, err := client.AdjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(dish.ID), Delta: -2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), resp.GetAvailableQuantity())
+
+	// Те же ошибки, что и в REST, но статусами gRPC
+	_, err = client.AdjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(dish.ID), Delta: -2})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.GetDish(ctx, &menuv1.GetDishRequest{Id: 999999})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCListDishesIncludeDeleted(t *testing.T) {
+	initDatabase()
+	client := startGRPC(t)
+	ctx := context.Background()
+
+	dish := Menu{Name: "gRPC deleted", Price: 5.0, Description: "Deleted", AvailableQuantity: 1, CategoryID: 1}
+	db.Create(&dish)
+	db.Delete(&dish)
+
+	listed := func(req *menuv1.ListDishesRequest) bool {
+		resp, err := client.ListDishes(ctx, req)
+		assert.NoError(t, err)
+		for _, item := range resp.GetDishes() {
+			if item.GetId() == uint32(dish.ID) {
+				return true
+			}
+		}
+		return false
+	}
+	assert.False(t, listed(&menuv1.ListDishesRequest{}))
+	assert.True(t, listed(&menuv1.ListDishesRequest{IncludeDeleted: true}))
+}
+
+// Отмена заказа с удалённым блюдом возвращает порции на склад
+func TestGRPCAdjustStockDeletedDish(t *testing.T) {
+	initDatabase()
+	client := startGRPC(t)
+	ctx := context.Background()
+
+	dish := Menu{Name: "gRPC deleted stock", Price: 5.0, Description: "Deleted", AvailableQuantity: 3, CategoryID: 1}
+	db.Create(&dish)
+	_, err := client.AdjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(dish.ID), Delta: -2})
+	assert.NoError(t, err)
+	db.Delete(&dish)
+
+	resp, err := client.AdjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(dish.ID), Delta: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), resp.GetAvailableQuantity())
+}
+
+// Ингредиент с уникальным именем: база между тестами не очищается
+func addTestIngredient(t *testing.T, router http.Handler, unit string, stock float64) Ingredient {
+	body, _ := json.Marshal(map[string]interface{}{"name": fmt.Sprintf("%s %d", t.Name(), time.Now().UnixNano()), "unit": unit, "stock": stock})
+	req, _ := http.NewRequest("POST", "/ingredients", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var ingredient Ingredient
+	json.Unmarshal(w.Body.Bytes(), &ingredient)
+	return ingredient
+}
+
+// Замена рецепта блюда без открытых заказов
+func setTestRecipe(t *testing.T, router http.Handler, dishID uint, items []map[string]interface{}) *httptest.ResponseRecorder {
+	startOrderService(t, `[]`)
+	body, _ := json.Marshal(items)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/menu/%d/recipe", dishID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	return serveWithSpec(t, router, req)
+}
+
+func TestRecipeDerivesPortions(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	beets := addTestIngredient(t, router, "g", 1000)
+	cream := addTestIngredient(t, router, "ml", 100)
+	dish := Menu{Name: "Борщ", Price: 300, Description: "Со сметаной", AvailableQuantity: 50, CategoryID: 1}
+	db.Create(&dish)
+
+	w := setTestRecipe(t, router, dish.ID, []map[string]interface{}{
+		{"ingredient_id": beets.ID, "quantity": 200},
+		{"ingredient_id": cream.ID, "quantity": 30},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Сметаны хватит на 3 порции, свёклы на 5
+	var stored Menu
+	db.First(&stored, dish.ID)
+	assert.Equal(t, 3, stored.AvailableQuantity)
+
+	// Поступление сметаны: теперь ограничивает свёкла
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/ingredients/%d/stock", cream.ID), bytes.NewBufferString(`{"delta": 500}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&stored, dish.ID)
+	assert.Equal(t, 5, stored.AvailableQuantity)
+
+	// Число порций блюда с рецептом вручную не задаётся
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/menu/%d", dish.ID), bytes.NewBufferString(`{"available_quantity": 40}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Списать больше, чем есть на складе, нельзя
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/ingredients/%d/stock", beets.ID), bytes.NewBufferString(`{"delta": -2000}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestSetRecipeValidation(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	salt := addTestIngredient(t, router, "g", 100)
+	dish := Menu{Name: "Recipe", Price: 5.0, Description: "Recipe", AvailableQuantity: 3, CategoryID: 1}
+	db.Create(&dish)
+
+	w := setTestRecipe(t, router, dish.ID, []map[string]interface{}{
+		{"ingredient_id": salt.ID, "quantity": 0},
+		{"ingredient_id": salt.ID, "quantity": 5},
+		{"ingredient_id": 999999, "quantity": 5},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"[0].quantity"`)
+	assert.Contains(t, w.Body.String(), `"[1].ingredient_id"`)
+	assert.Contains(t, w.Body.String(), `"[2].ingredient_id"`)
+}
+
+// Рецепт блюда с открытыми заказами не меняется: они зарезервированы по прежнему рецепту
+func TestSetRecipeWithOpenOrders(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	salt := addTestIngredient(t, router, "g", 100)
+	dish := Menu{Name: "Recipe with orders", Price: 5.0, Description: "Recipe", AvailableQuantity: 3, CategoryID: 1}
+	db.Create(&dish)
+	startOrderService(t, `[{"ID": 1, "status": "В ожидании"}]`)
+
+	body, _ := json.Marshal([]map[string]interface{}{{"ingredient_id": salt.ID, "quantity": 5}})
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/menu/%d/recipe", dish.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, router, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var count int64
+	db.Model(&RecipeItem{}).Where("menu_id = ?", dish.ID).Count(&count)
+	assert.Zero(t, count)
+}
+
+// Списание без резерва — расхождение учёта: оно не обнуляется молча, а отклоняется
+func TestGRPCConsumeStockWithoutReserve(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+	client := startGRPC(t)
+	ctx := context.Background()
+
+	flour := addTestIngredient(t, router, "g", 500)
+	dish := Menu{Name: "Оладьи", Price: 150, Description: "Пышные", CategoryID: 1}
+	db.Create(&dish)
+	setTestRecipe(t, router, dish.ID, []map[string]interface{}{{"ingredient_id": flour.ID, "quantity": 100}})
+
+	_, err := client.ConsumeStock(ctx, &menuv1.ConsumeStockRequest{Id: uint32(dish.ID), Quantity: 2})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	var stored Ingredient
+	db.First(&stored, flour.ID)
+	assert.Equal(t, 500.0, stored.Stock)
+	assert.Equal(t, 0.0, stored.Reserved)
+}
+
+// Заказ резервирует ингредиенты, кухня их списывает, отмена возвращает на склад
+func TestGRPCReserveAndConsumeIngredients(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+	client := startGRPC(t)
+	ctx := context.Background()
+
+	flour := addTestIngredient(t, router, "g", 500)
+	dish := Menu{Name: "Блины", Price: 200, Description: "Тонкие", CategoryID: 1}
+	db.Create(&dish)
+	setTestRecipe(t, router, dish.ID, []map[string]interface{}{{"ingredient_id": flour.ID, "quantity": 100}})
+
+	resp, err := client.AdjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(dish.ID), Delta: -2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), resp.GetAvailableQuantity())
+
+	_, err = client.AdjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(dish.ID), Delta: -4})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	resp, err = client.ConsumeStock(ctx, &menuv1.ConsumeStockRequest{Id: uint32(dish.ID), Quantity: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), resp.GetAvailableQuantity())
+
+	var stored Ingredient
+	db.First(&stored, flour.ID)
+	assert.Equal(t, 300.0, stored.Stock)
+	assert.Equal(t, 0.0, stored.Reserved)
+
+	// Отмена приготовленного заказа возвращает ингредиенты на склад
+	resp, err = client.AdjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(dish.ID), Delta: 2, Consumed: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), resp.GetAvailableQuantity())
+}
+
+// Кухня начинает готовить заказ, даже если блюдо уже удалили из меню
+func TestGRPCConsumeStockDeletedDish(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+	client := startGRPC(t)
+	ctx := context.Background()
+
+	flour := addTestIngredient(t, router, "g", 500)
+	dish := Menu{Name: "Оладьи", Price: 180, Description: "Пышные", CategoryID: 1}
+	db.Create(&dish)
+	setTestRecipe(t, router, dish.ID, []map[string]interface{}{{"ingredient_id": flour.ID, "quantity": 100}})
+
+	_, err := client.AdjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(dish.ID), Delta: -2})
+	assert.NoError(t, err)
+	db.Delete(&dish)
+
+	_, err = client.ConsumeStock(ctx, &menuv1.ConsumeStockRequest{Id: uint32(dish.ID), Quantity: 2})
+	assert.NoError(t, err)
+
+	var stored Ingredient
+	db.First(&stored, flour.ID)
+	assert.Equal(t, 300.0, stored.Stock)
+	assert.Equal(t, 0.0, stored.Reserved)
+}
+
+// Уведомления, полученные вебхуком
+func startStockWebhook(t *testing.T) <-chan stockEvent {
+	events := make(chan stockEvent, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event stockEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	t.Cleanup(server.Close)
+
+	previous := stockWebhookURL
+	stockWebhookURL = server.URL
+	t.Cleanup(func() { stockWebhookURL = previous })
+	return events
+}
+
+// Следующее уведомление о блюде; база между тестами не очищается, поэтому чужие блюда пропускаются
+func nextStockEvent(t *testing.T, events <-chan stockEvent, dishID uint) string {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.DishID == dishID {
+				return event.Event
+			}
+		case <-timeout:
+			t.Fatal("уведомление об остатке не пришло")
+			return ""
+		}
+	}
+}
+
+func TestStopListAndStockAlerts(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+	events := startStockWebhook(t)
+
+	req, _ := http.NewRequest("POST", "/menu", bytes.NewBufferString(`{"name": "Окрошка", "price": 250, "available_quantity": 3, "low_stock_threshold": 1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var dish Menu
+	json.Unmarshal(w.Body.Bytes(), &dish)
+	dishID := dish.ID
+	assert.Equal(t, stockInStock, dish.StockStatus)
+
+	adjust := func(delta int) Menu {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/menu/%d/stock", dishID), bytes.NewBufferString(fmt.Sprintf(`{"delta": %d}`, delta)))
+		req.Header.Set("Content-Type", "application/json")
+		w := serveWithSpec(t, router, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var dish Menu
+		json.Unmarshal(w.Body.Bytes(), &dish)
+		return dish
+	}
+	listed := func(path string, manager bool) bool {
+		req, _ := http.NewRequest("GET", path, nil)
+		if manager {
+			req.Header.Set("X-User-Role", "manager")
+		}
+		w := serveWithSpec(t, router, req)
+		var dishes []Menu
+		json.Unmarshal(w.Body.Bytes(), &dishes)
+		for _, dish := range dishes {
+			if dish.ID == dishID {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.Equal(t, stockLow, adjust(-2).StockStatus)
+	assert.Equal(t, eventLowStock, nextStockEvent(t, events, dishID))
+	assert.True(t, listed("/menu/stoplist", false))
+
+	// Закончившееся блюдо пропадает из меню гостя, но остаётся у менеджера
+	assert.Equal(t, stockSoldOut, adjust(-1).StockStatus)
+	assert.Equal(t, eventSoldOut, nextStockEvent(t, events, dishID))
+	assert.False(t, listed("/menu", false))
+	assert.True(t, listed("/menu", true))
+	assert.True(t, listed("/menu/stoplist", false))
+
+	assert.Equal(t, stockInStock, adjust(5).StockStatus)
+	assert.Equal(t, eventBackInStock, nextStockEvent(t, events, dishID))
+	assert.True(t, listed("/menu", false))
+	assert.False(t, listed("/menu/stoplist", false))
+}
+
+func TestDishAvailableAt(t *testing.T) {
+	season := func(date string) *string { return &date }
+	at := func(value string) time.Time {
+		parsed, _ := time.ParseInLocation("2006-01-02 15:04", value, menuLocation)
+		return parsed
+	}
+
+	// Бизнес-ланч по будням 12–16 и ночное меню 22–02 по пятницам
+	dish := Menu{Schedule: []ScheduleWindow{
+		{Weekdays: []int{1, 2, 3, 4, 5}, Start: "12:00", End: "16:00"},
+		{Weekdays: []int{5}, Start: "22:00", End: "02:00"},
+	}}
+	assert.True(t, dish.availableAt(at("2026-10-19 12:00")))  // понедельник
+	assert.False(t, dish.availableAt(at("2026-10-19 16:00"))) // конец окна не входит
+	assert.False(t, dish.availableAt(at("2026-10-18 13:00"))) // воскресенье
+	assert.True(t, dish.availableAt(at("2026-10-23 23:30")))  // пятница вечером
+	assert.True(t, dish.availableAt(at("2026-10-24 01:59")))  // ночь на субботу относится к пятнице
+	assert.False(t, dish.availableAt(at("2026-10-25 01:00"))) // ночь на воскресенье
+
+	// Сезон включает обе даты
+	okroshka := Menu{SeasonStart: season("2026-06-01"), SeasonEnd: season("2026-08-31")}
+	assert.False(t, okroshka.availableAt(at("2026-05-31 23:59")))
+	assert.True(t, okroshka.availableAt(at("2026-06-01 00:00")))
+	assert.True(t, okroshka.availableAt(at("2026-08-31 23:59")))
+	assert.False(t, okroshka.availableAt(at("2026-09-01 00:00")))
+}
+
+func TestScheduledMenu(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	dish := Menu{Name: "Сырники", Price: 250, Description: "Завтрак", AvailableQuantity: 10, CategoryID: 1}
+	db.Create(&dish)
+
+	body := `{"season_start": null, "season_end": null, "windows": [{"weekdays": [], "start": "08:00", "end": "11:00"}]}`
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/menu/%d/schedule", dish.ID), bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	listed := func(at time.Time) bool {
+		req, _ := http.NewRequest("GET", "/menu?at="+url.QueryEscape(at.Format(time.RFC3339)), nil)
+		w := serveWithSpec(t, router, req)
+		var dishes []Menu
+		json.Unmarshal(w.Body.Bytes(), &dishes)
+		for _, item := range dishes {
+			if item.ID == dish.ID {
+				return true
+			}
+		}
+		return false
+	}
+	assert.True(t, listed(time.Date(2026, 10, 19, 9, 30, 0, 0, menuLocation)))
+	assert.False(t, listed(time.Date(2026, 10, 19, 12, 0, 0, 0, menuLocation)))
+
+	// Неверное окно
+	body = `{"season_start": "2026-09-01", "season_end": "2026-06-01", "windows": [{"weekdays": [8], "start": "10:00", "end": "10:00"}]}`
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("/menu/%d/schedule", dish.ID), bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"season_end"`)
+	assert.Contains(t, w.Body.String(), `"windows[0].weekdays"`)
+	assert.Contains(t, w.Body.String(), `"windows[0].end"`)
+}
+
+func TestPriceHistory(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	dish := Menu{Name: "Пельмени", Price: 300, Description: "Домашние", AvailableQuantity: 10, CategoryID: 1}
+	createDish(db, &dish)
+	pricesPath := fmt.Sprintf("/menu/%d/prices", dish.ID)
+
+	patch := func(body string) {
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("/menu/%d", dish.ID), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := serveWithSpec(t, router, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	prices := func(query string) []PriceChange {
+		req, _ := http.NewRequest("GET", pricesPath+query, nil)
+		w := serveWithSpec(t, router, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var prices []PriceChange
+		json.Unmarshal(w.Body.Bytes(), &prices)
+		return prices
+	}
+	schedule := func(price float64, from time.Time) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"price": price, "effective_from": from, "changed_by": "manager1"})
+		req, _ := http.NewRequest("POST", pricesPath, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		return serveWithSpec(t, router, req)
+	}
+
+	// Повтор той же цены историю не меняет
+	beforeChange := time.Now()
+	patch(`{"price": 350}`)
+	patch(`{"price": 350, "description": "С маслом"}`)
+	history := prices("")
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, 350.0, history[0].Price)
+		assert.Equal(t, 300.0, history[1].Price)
+	}
+	if at := prices("?at=" + url.QueryEscape(beforeChange.Format(time.RFC3339Nano))); assert.Len(t, at, 1) {
+		assert.Equal(t, 300.0, at[0].Price)
+	}
+
+	// Запланированная цена видна в истории, но блюдо пока стоит по-старому
+	w := schedule(400, time.Now().Add(time.Hour))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var scheduled PriceChange
+	json.Unmarshal(w.Body.Bytes(), &scheduled)
+	assert.True(t, scheduled.Scheduled)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/menu/%d", dish.ID), nil)
+	w = serveWithSpec(t, router, req)
+	var current Menu
+	json.Unmarshal(w.Body.Bytes(), &current)
+	assert.Equal(t, 350.0, current.Price)
+	etag := w.Header().Get("ETag")
+
+	// Наступил момент вступления в силу: цена новая, а версия блюда от чтения не меняется
+	db.Model(&PriceChange{}).Where("id = ?", scheduled.ID).Update("effective_from", time.Now().Add(-time.Minute))
+	w = serveWithSpec(t, router, req)
+	json.Unmarshal(w.Body.Bytes(), &current)
+	assert.Equal(t, 400.0, current.Price)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	// Вступившую в силу цену отменить нельзя, запланированную — можно
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("%s/%d", pricesPath, scheduled.ID), nil)
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = schedule(450, time.Now().Add(24*time.Hour))
+	json.Unmarshal(w.Body.Bytes(), &scheduled)
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("%s/%d", pricesPath, scheduled.ID), nil)
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, prices(""), 3)
+
+	// Планировать можно только на будущее
+	w = schedule(500, time.Now().Add(-time.Hour))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCancelScheduledPriceInvalidID(t *testing.T) {
+	router := setupRouter()
+
+	// Запросы заведомо не соответствуют спецификации, поэтому без serveWithSpec
+	for _, path := range []string{"/menu/1/prices/1%20OR%201=1", "/menu/1%20OR%201=1/prices/1"} {
+		req, _ := http.NewRequest("DELETE", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), "invalid_id", path)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	initDatabase()
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	w := serveWithSpec(t, router, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"postgres":"ok"`)
+}
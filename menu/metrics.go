@@ -0,0 +1,23 @@
+package menu
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"log/slog"
+)
+
+// Блюда, которые закончились; значение считается запросом к базе при каждом сборе метрик
+var _ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+	Name: "dishes_out_of_stock",
+	Help: "Блюда без доступных порций.",
+}, func() float64 {
+	if db == nil {
+		return 0
+	}
+	var count int64
+	if err := db.Model(&Menu{}).Where("available_quantity <= 0").Count(&count).Error; err != nil {
+		slog.Error("ошибка подсчёта закончившихся блюд для метрик", "error", err)
+		return 0
+	}
+	return float64(count)
+})
@@ -0,0 +1,104 @@
+package menu
+
+import (
+	"c_keeper_go/apierr"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// Модификатор блюда: размер порции, добавка или убираемый ингредиент
+type Modifier struct {
+	ID         uint    `gorm:"primaryKey" json:"id"`
+	MenuID     uint    `gorm:"index" json:"menu_id"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`        // size, addon, removal
+	PriceDelta float64 `json:"price_delta"` // надбавка к цене блюда
+}
+
+// Типы модификаторов
+const (
+	modifierTypeSize    = "size"
+	modifierTypeAddon   = "addon"
+	modifierTypeRemoval = "removal"
+)
+
+var validModifierTypes = map[string]bool{
+	modifierTypeSize:    true,
+	modifierTypeAddon:   true,
+	modifierTypeRemoval: true,
+}
+
+// Получение модификаторов блюда
+func getModifiers(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var modifiers []Modifier
+	if err := requestDB(c).Where("menu_id = ?", id).Find(&modifiers).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, modifiers)
+}
+
+// Добавление модификатора к блюду
+func addModifier(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var modifier Modifier
+	if err := c.ShouldBindJSON(&modifier); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	if errs := modifier.validate(); len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	var dish Menu
+	if err := requestDB(c).First(&dish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
+		return
+	}
+
+	modifier.ID = 0
+	modifier.MenuID = dish.ID
+	if err := requestDB(c).Create(&modifier).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusCreated, modifier)
+}
+
+// Удаление модификатора блюда
+func deleteModifier(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	modifierID, err := strconv.Atoi(c.Param("modifierId"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	result := requestDB(c).Where("menu_id = ?", id).Delete(&Modifier{}, modifierID)
+	if result.Error != nil {
+		apierr.Abort(c, apierr.Internal(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierr.Abort(c, apierr.NotFound("modifier_not_found"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": successMessage(c, "modifier_deleted")})
+}
@@ -0,0 +1,220 @@
+package menu
+
+import (
+	"c_keeper_go/apierr"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Применение JSON Merge Patch (RFC 7386) к блюду.
+// Возвращает изменённые колонки и ошибки по полям; блюдо проверяется целиком теми же правилами, что и при создании
+func applyDishPatch(dish *Menu, raw map[string]json.RawMessage) (map[string]interface{}, apierr.FieldErrors) {
+	updates := map[string]interface{}{}
+	errs := apierr.FieldErrors{}
+
+	// decode разбирает значение поля; null по RFC 7386 сбрасывает его в нулевое значение
+	decode := func(field string, value json.RawMessage, target interface{}, nullable bool) bool {
+		if string(value) == "null" {
+			if !nullable {
+				errs.Add(field, "required", nil)
+				return false
+			}
+			return true
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			errs.Add(field, "invalid_type", nil)
+			return false
+		}
+		return true
+	}
+
+	for field, value := range raw {
+		switch field {
+		case "name":
+			var name string
+			if decode(field, value, &name, false) {
+				dish.Name = strings.TrimSpace(name)
+				updates["name"] = dish.Name
+			}
+		case "price":
+			var price float64
+			if decode(field, value, &price, false) {
+				dish.Price = price
+				updates["price"] = price
+			}
+		case "description":
+			var description string
+			if decode(field, value, &description, true) {
+				dish.Description = description
+				updates["description"] = description
+			}
+		case "category_id":
+			var categoryID uint
+			if decode(field, value, &categoryID, true) {
+				dish.CategoryID = categoryID
+				updates["category_id"] = categoryID
+			}
+		case "available_quantity":
+			if len(dish.Recipe) > 0 {
+				errs.Add(field, "from_recipe", nil)
+				continue
+			}
+			var quantity int
+			if decode(field, value, &quantity, false) {
+				dish.AvailableQuantity = quantity
+				updates["available_quantity"] = quantity
+			}
+		case "low_stock_threshold":
+			var threshold int
+			if decode(field, value, &threshold, false) {
+				dish.LowStockThreshold = threshold
+				updates["low_stock_threshold"] = threshold
+			}
+		default:
+			errs.Add(field, "unknown", nil)
+		}
+	}
+
+	// Проверяем только изменённые поля: остальные уже лежат в базе
+	for field, fieldErr := range dish.validate() {
+		if _, changed := raw[field]; changed {
+			errs.Add(field, fieldErr.Rule, fieldErr.Param)
+		}
+	}
+	return updates, errs
+}
+
+// Частичное обновление блюда (PATCH /menu/:id)
+func patchDish(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var raw map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&raw); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+
+	var dish Menu
+	if err := requestDB(c).Preload("Recipe").First(&dish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
+		return
+	}
+
+	updates, errs := applyDishPatch(&dish, raw)
+	if len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	if !checkIfMatch(c, dish) {
+		return
+	}
+
+	if len(updates) > 0 {
+		err = saveDishVersioned(requestDB(c), &dish, updates)
+		if err != nil {
+			apierr.Abort(c, err)
+			return
+		}
+	}
+
+	syncStockAlerts(c.Request.Context())
+	dish, err = findDish(requestDB(c), id)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.Header("ETag", dishETag(dish))
+	c.JSON(http.StatusOK, dish)
+}
+
+// Откат транзакции массового обновления при ошибках валидации
+var errValidation = errors.New("validation failed")
+
+// Массовое обновление блюд (PATCH /menu): массив merge-patch объектов с id,
+// применяется целиком в одной транзакции или не применяется вовсе
+func patchDishes(c *gin.Context) {
+	var items []map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&items); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	if len(items) == 0 {
+		apierr.Abort(c, apierr.BadRequest("nothing_to_update"))
+		return
+	}
+
+	ids := make([]uint, len(items))
+	errs := apierr.FieldErrors{}
+	seen := map[uint]bool{}
+	for i, item := range items {
+		var id uint
+		if err := json.Unmarshal(item["id"], &id); err != nil || id == 0 {
+			errs.Add(fmt.Sprintf("[%d].id", i), "required", nil)
+			continue
+		}
+		if seen[id] {
+			errs.Add(fmt.Sprintf("[%d].id", i), "duplicate", nil)
+			continue
+		}
+		seen[id] = true
+		delete(item, "id")
+		ids[i] = id
+	}
+	if len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	var missing uint
+	err := requestDB(c).Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			var dish Menu
+			if err := tx.Preload("Recipe").First(&dish, id).Error; err != nil {
+				missing = id
+				return err
+			}
+			updates, itemErrs := applyDishPatch(&dish, items[i])
+			errs.Merge(fmt.Sprintf("[%d].", i), itemErrs)
+			if len(itemErrs) > 0 || len(updates) == 0 {
+				continue
+			}
+			if err := saveDishVersioned(tx, &dish, updates); err != nil {
+				return err
+			}
+		}
+		if len(errs) > 0 {
+			return errValidation
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errValidation):
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		apierr.Abort(c, apierr.NotFound("dish_not_found_id", missing))
+		return
+	case err != nil:
+		apierr.Abort(c, err)
+		return
+	}
+
+	syncStockAlerts(c.Request.Context())
+	dishes, err := listDishes(requestDB(c).Where("id IN ?", ids))
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dishes)
+}
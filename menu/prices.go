@@ -0,0 +1,208 @@
+package menu
+
+import (
+	"c_keeper_go/apierr"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Цена блюда, действующая с EffectiveFrom до следующей записи.
+// Изменения задним числом не допускаются: история только дописывается
+type PriceChange struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	MenuID        uint      `gorm:"index:idx_price_changes_menu_effective" json:"menu_id"`
+	Price         float64   `json:"price"`
+	EffectiveFrom time.Time `gorm:"index:idx_price_changes_menu_effective" json:"effective_from"`
+	ChangedBy     string    `json:"changed_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	Scheduled     bool      `gorm:"-" json:"scheduled"` // цена ещё не вступила в силу
+}
+
+func (p *PriceChange) AfterFind(tx *gorm.DB) error {
+	p.Scheduled = p.EffectiveFrom.After(time.Now())
+	return nil
+}
+
+// Создание блюда вместе с первой записью истории — ценой, с которой оно создано
+func createDish(tx *gorm.DB, dish *Menu) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(dish).Error; err != nil {
+			return err
+		}
+		return tx.Create(&PriceChange{MenuID: dish.ID, Price: dish.Price, EffectiveFrom: time.Now()}).Error
+	})
+}
+
+// Записи истории для блюд, созданных до её появления: их прежние цены неизвестны,
+// поэтому текущая цена считается действующей с момента миграции
+func backfillPriceHistory(tx *gorm.DB) error {
+	return tx.Exec(`INSERT INTO price_changes (menu_id, price, effective_from, changed_by, created_at)
+		SELECT m.id, m.price, now(), '', now() FROM menus m
+		WHERE NOT EXISTS (SELECT 1 FROM price_changes p WHERE p.menu_id = m.id)`).Error
+}
+
+// Запись новой цены, действующей с этого момента; повтор текущей цены историю не засоряет
+func recordPrice(tx *gorm.DB, menuID uint, price float64) error {
+	return tx.Exec(`INSERT INTO price_changes (menu_id, price, effective_from, changed_by, created_at)
+		SELECT ?, ?, now(), '', now()
+		WHERE ?::numeric IS DISTINCT FROM (
+			SELECT price FROM price_changes WHERE menu_id = ? AND effective_from <= now()
+			ORDER BY effective_from DESC, id DESC LIMIT 1
+		)`, menuID, price, price, menuID).Error
+}
+
+// Действующие цены блюд — последние вступившие в силу записи истории. Запланированная цена
+// начинает действовать без записи в блюдо, поэтому чтение не меняет версию и ETag
+func resolvePrices(tx *gorm.DB, dishes []Menu) error {
+	if len(dishes) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(dishes))
+	for i, dish := range dishes {
+		ids[i] = dish.ID
+	}
+
+	var current []PriceChange
+	err := tx.Raw(`SELECT DISTINCT ON (menu_id) menu_id, price FROM price_changes
+		WHERE menu_id IN ? AND effective_from <= now()
+		ORDER BY menu_id, effective_from DESC, id DESC`, ids).Scan(&current).Error
+	if err != nil {
+		return err
+	}
+	prices := make(map[uint]float64, len(current))
+	for _, change := range current {
+		prices[change.MenuID] = change.Price
+	}
+	for i := range dishes {
+		if price, ok := prices[dishes[i].ID]; ok {
+			dishes[i].Price = price
+		}
+	}
+	return nil
+}
+
+// Тело запроса POST /menu/:id/prices
+type priceInput struct {
+	Price         *float64   `json:"price"`
+	EffectiveFrom *time.Time `json:"effective_from"`
+	ChangedBy     string     `json:"changed_by"`
+}
+
+func (p priceInput) validate(now time.Time) apierr.FieldErrors {
+	errs := apierr.FieldErrors{}
+	if p.Price == nil {
+		errs.Add("price", "required", nil)
+	} else if *p.Price < 0 {
+		errs.Add("price", "min", 0)
+	}
+	if p.EffectiveFrom == nil {
+		errs.Add("effective_from", "required", nil)
+	} else if !p.EffectiveFrom.After(now) {
+		errs.Add("effective_from", "in_future", nil)
+	}
+	validateString(errs, "changed_by", p.ChangedBy, false, 255)
+	return errs
+}
+
+// История цен блюда, новые сверху, включая запланированные (GET /menu/:id/prices).
+// ?at=<RFC 3339> оставляет одну запись — цену, действовавшую в этот момент
+func getPrices(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	// История нужна и для удалённых блюд: по ней сверяются старые счета
+	var dish Menu
+	if err := requestDB(c).Unscoped().First(&dish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
+		return
+	}
+
+	query := requestDB(c).Where("menu_id = ?", dish.ID).Order("effective_from DESC, id DESC")
+	if value := c.Query("at"); value != "" {
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			errs := apierr.FieldErrors{}
+			errs.Add("at", "invalid_type", nil)
+			apierr.Abort(c, apierr.Validation(errs))
+			return
+		}
+		query = query.Where("effective_from <= ?", at).Limit(1)
+	}
+
+	var prices []PriceChange
+	if err := query.Find(&prices).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, prices)
+}
+
+// Планирование изменения цены (POST /menu/:id/prices); текущая цена меняется через PUT или PATCH блюда
+func schedulePrice(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var input priceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	if errs := input.validate(time.Now()); len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	var dish Menu
+	if err := requestDB(c).First(&dish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
+		return
+	}
+
+	change := PriceChange{MenuID: dish.ID, Price: *input.Price, EffectiveFrom: *input.EffectiveFrom, ChangedBy: input.ChangedBy}
+	if err := requestDB(c).Create(&change).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	change.Scheduled = true
+	c.JSON(http.StatusCreated, change)
+}
+
+// Отмена запланированного изменения цены (DELETE /menu/:id/prices/:priceId)
+func cancelScheduledPrice(c *gin.Context) {
+	menuID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	priceID, err := strconv.Atoi(c.Param("priceId"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	var change PriceChange
+	if err := requestDB(c).Where("menu_id = ? AND id = ?", menuID, priceID).First(&change).Error; err != nil {
+		apierr.Abort(c, dbError(err, "price_not_found"))
+		return
+	}
+
+	// Вступившая в силу цена уже могла попасть в заказы; условие в WHERE закрывает гонку со вступлением
+	result := requestDB(c).Where("id = ? AND effective_from > now()", change.ID).Delete(&PriceChange{})
+	if result.Error != nil {
+		apierr.Abort(c, apierr.Internal(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierr.Abort(c, apierr.Conflict("price_already_effective"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": successMessage(c, "price_cancelled")})
+}
@@ -0,0 +1,234 @@
+package menu
+
+import (
+	"c_keeper_go/apierr"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Часовой пояс ресторана: по нему проверяются окна расписания и даты сезона
+var menuLocation = loadMenuLocation(getEnv("MENU_TIMEZONE", "UTC"))
+
+func loadMenuLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// Окно, в которое блюдо можно заказать, например завтрак 08:00–11:00.
+// Если конец не позже начала, окно переходит через полночь и относится к дню начала
+type ScheduleWindow struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	MenuID   uint   `gorm:"index" json:"menu_id"`
+	Weekdays []int  `gorm:"type:jsonb;serializer:json" json:"weekdays"` // 1 — понедельник … 7 — воскресенье; пусто — каждый день
+	Start    string `gorm:"size:5" json:"start"`                        // HH:MM
+	End      string `gorm:"size:5" json:"end"`                          // HH:MM
+}
+
+// Расписание блюда (тело и ответ /menu/:id/schedule)
+type dishSchedule struct {
+	SeasonStart *string          `json:"season_start"` // YYYY-MM-DD включительно; null — без ограничения
+	SeasonEnd   *string          `json:"season_end"`
+	Windows     []ScheduleWindow `json:"windows"`
+}
+
+// Минуты от полуночи для времени HH:MM
+func clockMinutes(value string) (int, bool) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// ISO-номер дня недели: 1 — понедельник … 7 — воскресенье
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+func (w ScheduleWindow) onDay(t time.Time) bool {
+	return len(w.Weekdays) == 0 || slices.Contains(w.Weekdays, isoWeekday(t))
+}
+
+// Попадает ли момент (в часовом поясе ресторана) в окно
+func (w ScheduleWindow) contains(t time.Time) bool {
+	start, _ := clockMinutes(w.Start)
+	end, _ := clockMinutes(w.End)
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return w.onDay(t) && minute >= start && minute < end
+	}
+	// Окно через полночь: вечерняя часть относится к этому дню, ночная — к предыдущему
+	if minute >= start {
+		return w.onDay(t)
+	}
+	return minute < end && w.onDay(t.AddDate(0, 0, -1))
+}
+
+// Можно ли заказать блюдо в момент at: дата в пределах сезона и время в одном из окон.
+// Блюдо без окон доступно весь день
+func (m Menu) availableAt(at time.Time) bool {
+	local := at.In(menuLocation)
+	day := local.Format(time.DateOnly)
+	if m.SeasonStart != nil && day < *m.SeasonStart {
+		return false
+	}
+	if m.SeasonEnd != nil && day > *m.SeasonEnd {
+		return false
+	}
+	if len(m.Schedule) == 0 {
+		return true
+	}
+	for _, window := range m.Schedule {
+		if window.contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// Блюда, которые можно заказать в момент at
+func filterAvailable(dishes []Menu, at time.Time) []Menu {
+	available := make([]Menu, 0, len(dishes))
+	for _, dish := range dishes {
+		if dish.availableAt(at) {
+			available = append(available, dish)
+		}
+	}
+	return available
+}
+
+func (s dishSchedule) validate() apierr.FieldErrors {
+	errs := apierr.FieldErrors{}
+	for field, value := range map[string]*string{"season_start": s.SeasonStart, "season_end": s.SeasonEnd} {
+		if value == nil {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, *value); err != nil {
+			errs.Add(field, "invalid_type", nil)
+		}
+	}
+	if len(errs) == 0 && s.SeasonStart != nil && s.SeasonEnd != nil && *s.SeasonEnd < *s.SeasonStart {
+		errs.Add("season_end", "before_start", nil)
+	}
+
+	for i, window := range s.Windows {
+		field := fmt.Sprintf("windows[%d].", i)
+		start, okStart := clockMinutes(window.Start)
+		end, okEnd := clockMinutes(window.End)
+		if !okStart {
+			errs.Add(field+"start", "invalid_type", nil)
+		}
+		if !okEnd {
+			errs.Add(field+"end", "invalid_type", nil)
+		}
+		if okStart && okEnd && start == end {
+			errs.Add(field+"end", "same_as_start", nil)
+		}
+		seen := map[int]bool{}
+		for _, day := range window.Weekdays {
+			if day < 1 || day > 7 {
+				errs.Add(field+"weekdays", "one_of", "1–7")
+				break
+			}
+			if seen[day] {
+				errs.Add(field+"weekdays", "duplicate", nil)
+				break
+			}
+			seen[day] = true
+		}
+	}
+	return errs
+}
+
+// Расписание блюда (GET /menu/:id/schedule)
+func getSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	dish, err := findDish(requestDB(c), id)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, scheduleOf(dish))
+}
+
+func scheduleOf(dish Menu) dishSchedule {
+	windows := dish.Schedule
+	if windows == nil {
+		windows = []ScheduleWindow{}
+	}
+	return dishSchedule{SeasonStart: dish.SeasonStart, SeasonEnd: dish.SeasonEnd, Windows: windows}
+}
+
+// Замена расписания блюда (PUT /menu/:id/schedule): сезон и окна доступности.
+// Пустой список окон и null в датах снимают ограничения
+func setSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+
+	var schedule dishSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	if errs := schedule.validate(); len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	var dish Menu
+	if err := requestDB(c).First(&dish, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "dish_not_found"))
+		return
+	}
+
+	err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Menu{}).Where("id = ?", dish.ID).Updates(map[string]interface{}{
+			"season_start": schedule.SeasonStart,
+			"season_end":   schedule.SeasonEnd,
+			"version":      gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("menu_id = ?", dish.ID).Delete(&ScheduleWindow{}).Error; err != nil {
+			return err
+		}
+		for i := range schedule.Windows {
+			schedule.Windows[i].ID = 0
+			schedule.Windows[i].MenuID = dish.ID
+		}
+		if len(schedule.Windows) == 0 {
+			return nil
+		}
+		return tx.Create(&schedule.Windows).Error
+	})
+	if err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+
+	dish, err = findDish(requestDB(c), dish.ID)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, scheduleOf(dish))
+}
@@ -0,0 +1,156 @@
+package menu
+
+import (
+	"bytes"
+	"c_keeper_go/apierr"
+	"c_keeper_go/logging"
+	"c_keeper_go/tracing"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Уровни остатка блюда
+const (
+	stockInStock = "in_stock"
+	stockLow     = "low"      // порций не больше порога low_stock_threshold
+	stockSoldOut = "sold_out" // порций нет, блюдо в стоп-листе
+)
+
+// Уровень остатка тем же правилом, что и stockLevelSQL
+func stockLevel(available, threshold int) string {
+	switch {
+	case available <= 0:
+		return stockSoldOut
+	case available <= threshold:
+		return stockLow
+	default:
+		return stockInStock
+	}
+}
+
+const stockLevelSQL = `CASE WHEN available_quantity <= 0 THEN 'sold_out'
+	WHEN available_quantity <= low_stock_threshold THEN 'low' ELSE 'in_stock' END`
+
+// Уровень остатка в ответе считается при каждом чтении блюда из базы
+func (m *Menu) AfterFind(tx *gorm.DB) error {
+	m.setStockStatus()
+	return nil
+}
+
+// Новое блюдо получает уровень остатка
+func (m *Menu) AfterCreate(tx *gorm.DB) error {
+	m.setStockStatus()
+	return nil
+}
+
+func (m *Menu) setStockStatus() {
+	m.StockStatus = stockLevel(m.AvailableQuantity, m.LowStockThreshold)
+}
+
+// Адрес, на который уходят уведомления об остатках (POST JSON); пустой — только лог
+var stockWebhookURL = getEnv("STOCK_WEBHOOK_URL", "")
+
+var webhookClient = &http.Client{
+	Timeout:   time.Duration(getEnvInt("STOCK_WEBHOOK_TIMEOUT_SECONDS", 5)) * time.Second,
+	Transport: tracing.Transport(nil),
+}
+
+var stockAlerts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "dish_stock_alerts_total",
+	Help: "Уведомления о смене уровня остатка блюда по событиям.",
+}, []string{"event"})
+
+// События уведомлений
+const (
+	eventLowStock    = "low_stock"
+	eventSoldOut     = "sold_out"
+	eventBackInStock = "back_in_stock"
+)
+
+var levelEvents = map[string]string{stockLow: eventLowStock, stockSoldOut: eventSoldOut, stockInStock: eventBackInStock}
+
+// Тело уведомления об остатке
+type stockEvent struct {
+	Event             string    `json:"event"`
+	DishID            uint      `json:"dish_id"`
+	Name              string    `json:"name"`
+	AvailableQuantity int       `json:"available_quantity"`
+	LowStockThreshold int       `json:"low_stock_threshold"`
+	At                time.Time `json:"at"`
+}
+
+// Сверка уровней остатка с последними отправленными уведомлениями. Вызывается после
+// любого изменения порций или порога: каждое блюдо, чей уровень сменился, уведомляется ровно один раз,
+// даже при параллельных изменениях — строку, уже обновлённую другим запросом, UPDATE не вернёт.
+// Ошибки только логируются: изменение остатка к этому моменту уже сохранено
+func syncStockAlerts(ctx context.Context) {
+	var changed []Menu
+	err := db.WithContext(ctx).Raw(`UPDATE menus SET stock_alert = ` + stockLevelSQL + `
+		WHERE deleted_at IS NULL AND stock_alert <> ` + stockLevelSQL + `
+		RETURNING id, name, available_quantity, low_stock_threshold, stock_alert`).Scan(&changed).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "ошибка проверки остатков блюд", "error", err)
+		return
+	}
+
+	for _, dish := range changed {
+		event := stockEvent{
+			Event:             levelEvents[dish.StockAlert],
+			DishID:            dish.ID,
+			Name:              dish.Name,
+			AvailableQuantity: dish.AvailableQuantity,
+			LowStockThreshold: dish.LowStockThreshold,
+			At:                time.Now(),
+		}
+		stockAlerts.WithLabelValues(event.Event).Inc()
+		slog.InfoContext(ctx, "уровень остатка блюда изменился", "event", event.Event, "dish_id", event.DishID, "available_quantity", event.AvailableQuantity)
+		if stockWebhookURL != "" {
+			// Уведомление не задерживает ответ на запрос, изменивший остаток
+			go sendStockEvent(context.WithoutCancel(ctx), stockWebhookURL, event)
+		}
+	}
+}
+
+// Отправка уведомления на вебхук; неудача только логируется
+func sendStockEvent(ctx context.Context, url string, event stockEvent) {
+	body, _ := json.Marshal(event)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		slog.ErrorContext(ctx, "ошибка уведомления об остатке", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	logging.SetRequestHeader(req)
+
+	resp, err := webhookClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			err = fmt.Errorf("вебхук вернул статус: %d", resp.StatusCode)
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "ошибка уведомления об остатке", "event", event.Event, "dish_id", event.DishID, "error", err)
+	}
+}
+
+// Стоп-лист (GET /menu/stoplist): закончившиеся блюда и блюда с остатком не выше порога,
+// сначала закончившиеся
+func getStopList(c *gin.Context) {
+	dishes, err := listDishes(requestDB(c).
+		Where("available_quantity <= 0 OR available_quantity <= low_stock_threshold").
+		Order("available_quantity, name"))
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dishes)
+}
@@ -0,0 +1,61 @@
+package menu
+
+import (
+	"c_keeper_go/apierr"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Проверка строки: обязательность и максимальная длина
+func validateString(errs apierr.FieldErrors, field, value string, required bool, maxLength int) {
+	if required && strings.TrimSpace(value) == "" {
+		errs.Add(field, "required", nil)
+		return
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		errs.Add(field, "max_length", maxLength)
+	}
+}
+
+func (cat Category) validate() apierr.FieldErrors {
+	errs := apierr.FieldErrors{}
+	validateString(errs, "name", cat.Name, true, 255)
+	return errs
+}
+
+func (m Modifier) validate() apierr.FieldErrors {
+	errs := apierr.FieldErrors{}
+	validateString(errs, "name", m.Name, true, 255)
+	if !validModifierTypes[m.Type] {
+		errs.Add("type", "one_of", strings.Join([]string{modifierTypeSize, modifierTypeAddon, modifierTypeRemoval}, ", "))
+	}
+	// Убрать ингредиент можно только бесплатно
+	if m.Type == modifierTypeRemoval && m.PriceDelta != 0 {
+		errs.Add("price_delta", "removal_price", nil)
+	}
+	return errs
+}
+
+func (m Menu) validate() apierr.FieldErrors {
+	errs := apierr.FieldErrors{}
+	validateString(errs, "name", m.Name, true, 255)
+	validateString(errs, "description", m.Description, false, 2000)
+	if m.Price < 0 {
+		errs.Add("price", "min", 0)
+	}
+	if m.AvailableQuantity < 0 {
+		errs.Add("available_quantity", "min", 0)
+	}
+	if m.LowStockThreshold < 0 {
+		errs.Add("low_stock_threshold", "min", 0)
+	}
+	// Категорию можно создать вместе с блюдом
+	if m.Category != (Category{}) {
+		errs.Merge("category.", m.Category.validate())
+	}
+	for i, modifier := range m.Modifiers {
+		errs.Merge(fmt.Sprintf("modifiers[%d].", i), modifier.validate())
+	}
+	return errs
+}
@@ -0,0 +1,59 @@
+package menu
+
+import (
+	"c_keeper_go/apierr"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// Блюдо изменили после того, как клиент его прочитал
+var errStaleDish = apierr.PreconditionFailed("dish_modified")
+
+// ETag блюда — его версия в кавычках
+func dishETag(dish Menu) string {
+	return fmt.Sprintf(`"%d"`, dish.Version)
+}
+
+// Проверка If-Match: true, если заголовка нет или он совпадает с текущей версией.
+// При несовпадении сразу отвечает 412
+func checkIfMatch(c *gin.Context, dish Menu) bool {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == dishETag(dish) {
+			return true
+		}
+	}
+	c.Header("ETag", dishETag(dish))
+	apierr.Abort(c, errStaleDish)
+	return false
+}
+
+// Сохранение полей блюда, только если версия в базе не изменилась с момента чтения.
+// Новая цена записывается в историю цен
+func saveDishVersioned(tx *gorm.DB, dish *Menu, fields map[string]interface{}) error {
+	fields["version"] = dish.Version + 1
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Menu{}).Where("id = ? AND version = ?", dish.ID, dish.Version).Updates(fields)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errStaleDish
+		}
+		if price, ok := fields["price"].(float64); ok {
+			return recordPrice(tx, dish.ID, price)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	dish.Version++
+	dish.setStockStatus()
+	return nil
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"c_keeper_go/gormhooks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+	"time"
+)
+
+var dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "db_query_duration_seconds",
+	Help:    "Время запросов к базе данных по операциям и таблицам.",
+	Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+}, []string{"operation", "table"})
+
+const startKey = "metrics:start"
+
+// InstrumentGORM добавляет в GORM колбэки, измеряющие время каждого запроса
+func InstrumentGORM(db *gorm.DB) error {
+	return gormhooks.Around(db, "metrics",
+		func(operation string, tx *gorm.DB) {
+			tx.InstanceSet(startKey, time.Now())
+		},
+		func(operation string, tx *gorm.DB) {
+			start, ok := tx.InstanceGet(startKey)
+			if !ok {
+				return
+			}
+			dbQueryDuration.
+				WithLabelValues(operation, tx.Statement.Table).
+				Observe(time.Since(start.(time.Time)).Seconds())
+		},
+	)
+}
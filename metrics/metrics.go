@@ -0,0 +1,63 @@
+// Package metrics содержит общие метрики Prometheus сервисов: время HTTP-запросов
+// по маршрутам, время запросов к базе и исходящих вызовов gRPC, а также /metrics.
+package metrics
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"time"
+)
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Время обработки HTTP-запросов по маршрутам.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	grpcClientDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_client_request_duration_seconds",
+		Help:    "Время исходящих вызовов gRPC по методам и кодам ответа.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+)
+
+// Handler отдаёт метрики в формате Prometheus (GET /metrics)
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// Middleware измеряет время обработки запроса. Маршрут берётся из шаблона (/order/:id),
+// чтобы число рядов не зависело от идентификаторов в пути
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// UnaryClientInterceptor измеряет время исходящих вызовов gRPC (например, order -> menu)
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		grpcClientDuration.
+			WithLabelValues(method, status.Code(err).String()).
+			Observe(time.Since(start).Seconds())
+		return err
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Текст, который отдаёт /metrics
+func scrape(t *testing.T) string {
+	r := gin.New()
+	r.GET("/metrics", Handler())
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMiddlewareUsesRouteTemplate(t *testing.T) {
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/order/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/order/1", "/order/2", "/missing"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrape(t)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/order/:id",status="200"} 2`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, body, `route="/order/1"`)
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	interceptor := UnaryClientInterceptor()
+	method := "/ckeeper.menu.v1.MenuService/AdjustStock"
+
+	err := interceptor(context.Background(), method, nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return status.Error(codes.FailedPrecondition, "not enough stock")
+		})
+
+	assert.Error(t, err)
+	assert.Contains(t, scrape(t), `grpc_client_request_duration_seconds_count{code="FailedPrecondition",method="/ckeeper.menu.v1.MenuService/AdjustStock"} 1`)
+}
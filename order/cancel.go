@@ -0,0 +1,138 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"context"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Коды причин отмены заказа
+const (
+	cancelReasonGuestChangedMind = "guest_changed_mind"
+	cancelReasonKitchenError     = "kitchen_error"
+	cancelReasonOutOfStock       = "out_of_stock"
+)
+
+var validCancelReasons = map[string]bool{
+	cancelReasonGuestChangedMind: true,
+	cancelReasonKitchenError:     true,
+	cancelReasonOutOfStock:       true,
+}
+
+// Отмена заказа с указанием причины
+func cancelOrder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	var order Order
+
+	if err := requestDB(c).First(&order, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "order_not_found"))
+		return
+	}
+
+	var cancelRequest struct {
+		Reason      string `json:"reason"`
+		CancelledBy string `json:"cancelled_by"`
+		Cooked      bool   `json:"cooked"` // блюдо уже приготовлено
+	}
+	if err := c.ShouldBindJSON(&cancelRequest); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+
+	if !checkIfMatch(c, order) {
+		return
+	}
+
+	stockReturned, err := applyCancel(c.Request.Context(), &order, cancelRequest.Reason, cancelRequest.CancelledBy, cancelRequest.Cooked)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+
+	c.Header("ETag", orderETag(order))
+	c.JSON(http.StatusOK, gin.H{
+		"message":        successMessage(c, "order_cancelled_ok"),
+		"order":          order,
+		"stock_returned": stockReturned,
+	})
+}
+
+// Отмена заказа: возврат порций на склад, сохранение и запись в историю; общая логика для REST и gRPC.
+// Возвращает, были ли порции возвращены на склад
+func applyCancel(ctx context.Context, order *Order, reason, cancelledBy string, cooked bool) (bool, error) {
+	errs := apierr.FieldErrors{}
+	if !validCancelReasons[reason] {
+		errs.Add("reason", "one_of", strings.Join([]string{cancelReasonGuestChangedMind, cancelReasonKitchenError, cancelReasonOutOfStock}, ", "))
+	}
+	validateString(errs, "cancelled_by", cancelledBy, true, 255)
+	if len(errs) > 0 {
+		return false, apierr.Validation(errs)
+	}
+
+	if slices.Contains(closedStatuses, order.Status) {
+		return false, apierr.Conflict("order_closed")
+	}
+
+	// Порции возвращаются на склад, только если блюдо не готовили
+	// и его действительно есть в наличии
+	returnStock := !cooked && reason != cancelReasonOutOfStock
+	restock := adjustDishStock
+	if order.StockConsumed {
+		// Порции уже списаны кухней: ингредиенты возвращаются на склад
+		restock = adjustConsumedStock
+	}
+	if returnStock {
+		if err := restock(ctx, order.MenuID, order.Quantity); err != nil {
+			return false, err
+		}
+	}
+	// Иначе резерв не должен повиснуть: приготовленное блюдо и отсутствующие продукты списываются
+	writeOff := !returnStock && !order.StockConsumed
+	if writeOff {
+		if err := consumeDishStock(ctx, order.MenuID, order.Quantity); err != nil {
+			return false, err
+		}
+	}
+
+	changes := map[string]fieldChange{
+		"status": {From: order.Status, To: statusCancelled},
+		"reason": {From: nil, To: reason},
+	}
+	before := *order
+	now := time.Now()
+	order.Status = statusCancelled
+	order.CancelReason = reason
+	order.CancelledBy = cancelledBy
+	order.CancelledAt = &now
+	order.Wasted = cooked
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := saveOrderVersioned(tx, order); err != nil {
+			return err
+		}
+		return recordHistory(tx, order.ID, historyActionCancelled, changes, cancelledBy)
+	})
+	if err != nil {
+		// Заказ не отменён — снова резервируем возвращённые порции
+		if returnStock {
+			restock(context.WithoutCancel(ctx), order.MenuID, -order.Quantity)
+		}
+		if writeOff {
+			consumeDishStock(context.WithoutCancel(ctx), order.MenuID, -order.Quantity)
+		}
+		*order = before
+		return false, err
+	}
+	ordersCancelled.WithLabelValues(reason).Inc()
+	return returnStock, nil
+}
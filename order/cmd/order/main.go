@@ -0,0 +1,40 @@
+// Команда order запускает сервис заказов: HTTP API и gRPC API с настройкой из переменных окружения
+package main
+
+import (
+	"c_keeper_go/logging"
+	"c_keeper_go/server"
+	"c_keeper_go/tracing"
+	"context"
+	"log/slog"
+	"net/http"
+	"order"
+)
+
+func main() {
+	logging.Setup("order")
+	shutdownTracing, err := tracing.Setup("order")
+	if err != nil {
+		logging.Fatal("ошибка настройки трейсинга", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	config := order.ConfigFromEnv()
+	if err := order.Setup(config); err != nil {
+		logging.Fatal("ошибка запуска сервиса", "error", err)
+	}
+
+	// gRPC API работает рядом с REST на отдельном порту; по SIGTERM оба сервера
+	// перестают принимать подключения и дорабатывают начатые запросы
+	srv := server.Server{
+		HTTP:            &http.Server{Addr: config.HTTPAddr, Handler: order.NewRouter()},
+		GRPC:            order.NewGRPCServer(),
+		GRPCAddr:        config.GRPCAddr,
+		ShutdownTimeout: config.ShutdownTimeout,
+		OnShutdown:      order.Shutdown,
+	}
+	if err := srv.Run(); err != nil {
+		slog.Error("сервер остановлен с ошибкой", "error", err)
+	}
+	order.Close()
+}
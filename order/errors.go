@@ -0,0 +1,121 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Сообщения сервиса: ошибки и ответы об успешных операциях; общие (internal_error, validation_failed, field_*) заданы в apierr
+var messages = apierr.Messages{
+	"ru": {
+		"invalid_id":               "Некорректный ID",
+		"order_not_found":          "Заказ не найден",
+		"order_not_deleted":        "Заказ не удалён",
+		"order_cancelled":          "Заказ отменен",
+		"order_closed":             "Заказ уже закрыт",
+		"order_not_editable":       "Заказ уже нельзя изменить",
+		"order_modified":           "Заказ был изменен, обновите данные",
+		"order_number_assigned":    "Номер заказа назначается сервером",
+		"invalid_status":           "Некорректный статус",
+		"dish_not_found":           "Блюдо не найдено в меню",
+		"not_enough_stock":         "Недостаточно порций блюда",
+		"dish_outside_schedule":    "Блюдо «%v» сейчас не подаётся",
+		"menu_unavailable":         "Сервис меню недоступен",
+		"idempotency_key_reused":   "Idempotency-Key уже использован с другим запросом",
+		"idempotency_key_in_usage": "Запрос с этим Idempotency-Key ещё обрабатывается",
+		"reports_forbidden":        "Отчёты доступны только менеджеру",
+		"shifts_forbidden":         "Смены доступны только менеджеру",
+		"shift_not_found":          "Смена не найдена",
+		"shift_not_open":           "Смена не открыта",
+		"shift_already_open":       "Смена уже открыта",
+		"shift_has_open_checks":    "Нельзя закрыть смену: неоплаченных счетов — %v",
+		"nothing_to_pay":           "У стола %v нет завершённых неоплаченных заказов",
+		"order_paid":               "Заказ уже оплачен",
+		"tables_forbidden":         "Столы добавляет только менеджер",
+		"table_not_found":          "Стол не найден",
+		"table_number_taken":       "Стол с номером %v уже есть",
+		"table_occupied":           "Стол ещё занят",
+		"table_has_open_orders":    "У стола есть неоплаченные заказы",
+		"reservation_not_found":    "Бронь не найдена",
+		"reservation_conflict":     "Стол уже забронирован на это время (бронь с %v)",
+		"reservation_not_booked":   "Бронь уже закрыта",
+		"reservation_not_started":  "Время брони ещё не наступило",
+
+		"field_modifier_unknown":   "модификатор %v не относится к блюду",
+		"field_modifier_duplicate": "модификатор %v указан дважды",
+		"field_modifier_one_size":  "можно выбрать только один размер",
+		"field_after_from":         "должно быть позже from",
+		"field_max":                "должно быть не больше %v",
+
+		"order_created":        "Заказ успешно создан",
+		"order_status_updated": "Статус заказа обновлен",
+		"order_deleted":        "Заказ успешно удалён",
+		"order_restored":       "Заказ восстановлен",
+		"order_updated":        "Заказ изменен",
+		"order_unchanged":      "Изменений нет",
+		"order_cancelled_ok":   "Заказ отменен",
+	},
+	"en": {
+		"invalid_id":               "Invalid ID",
+		"order_not_found":          "Order not found",
+		"order_not_deleted":        "Order is not deleted",
+		"order_cancelled":          "Order is cancelled",
+		"order_closed":             "Order is already closed",
+		"order_not_editable":       "Order can no longer be changed",
+		"order_modified":           "Order was modified, reload and retry",
+		"order_number_assigned":    "Order number is assigned by the server",
+		"invalid_status":           "Invalid status",
+		"dish_not_found":           "Dish not found in the menu",
+		"not_enough_stock":         "Not enough portions of the dish",
+		"dish_outside_schedule":    "Dish %q is not served at this time",
+		"menu_unavailable":         "Menu service is unavailable",
+		"idempotency_key_reused":   "Idempotency-Key was already used with a different request",
+		"idempotency_key_in_usage": "Request with this Idempotency-Key is still being processed",
+		"reports_forbidden":        "Reports are available to managers only",
+		"shifts_forbidden":         "Shifts are available to managers only",
+		"shift_not_found":          "Shift not found",
+		"shift_not_open":           "No shift is open",
+		"shift_already_open":       "A shift is already open",
+		"shift_has_open_checks":    "Shift cannot be closed: %v unpaid checks",
+		"nothing_to_pay":           "Table %v has no completed unpaid orders",
+		"order_paid":               "Order is already paid",
+		"tables_forbidden":         "Only managers can add tables",
+		"table_not_found":          "Table not found",
+		"table_number_taken":       "Table number %v already exists",
+		"table_occupied":           "Table is still occupied",
+		"table_has_open_orders":    "Table has unpaid orders",
+		"reservation_not_found":    "Reservation not found",
+		"reservation_conflict":     "Table is already booked for this time (reservation from %v)",
+		"reservation_not_booked":   "Reservation is already closed",
+		"reservation_not_started":  "Reservation has not started yet",
+
+		"field_modifier_unknown":   "modifier %v does not belong to the dish",
+		"field_modifier_duplicate": "modifier %v is listed twice",
+		"field_modifier_one_size":  "only one size can be chosen",
+		"field_after_from":         "must be later than from",
+		"field_max":                "must be at most %v",
+
+		"order_created":        "Order created",
+		"order_status_updated": "Order status updated",
+		"order_deleted":        "Order deleted",
+		"order_restored":       "Order restored",
+		"order_updated":        "Order updated",
+		"order_unchanged":      "Nothing changed",
+		"order_cancelled_ok":   "Order cancelled",
+	},
+}
+
+// Ошибка базы данных: отсутствие записи — 404 с кодом notFoundCode, остальное — внутренняя ошибка
+func dbError(err error, notFoundCode string, args ...interface{}) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apierr.NotFound(notFoundCode, args...)
+	}
+	return apierr.Internal(err)
+}
+
+// Сообщение об успешной операции на языке запроса (Accept-Language)
+func successMessage(c *gin.Context, code string) string {
+	return messages.Localize(apierr.RequestLanguage(c), code)
+}
@@ -0,0 +1,140 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"c_keeper_go/logging"
+	orderv1 "c_keeper_go/proto/order/v1"
+	"c_keeper_go/tracing"
+	"context"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strconv"
+)
+
+// gRPC-сервер заказов; бизнес-логика общая с обработчиками gin
+type orderServer struct {
+	orderv1.UnimplementedOrderServiceServer
+}
+
+func (orderServer) CreateOrder(ctx context.Context, req *orderv1.CreateOrderRequest) (*orderv1.Order, error) {
+	order := Order{
+		MenuID:   uint(req.GetMenuId()),
+		Quantity: int(req.GetQuantity()),
+		TableID:  uint(req.GetTableId()),
+		Notes:    req.GetNotes(),
+	}
+	for _, id := range req.GetModifierIds() {
+		order.ModifierIDs = append(order.ModifierIDs, uint(id))
+	}
+	if err := placeOrder(ctx, &order); err != nil {
+		return nil, err
+	}
+	return orderToProto(order), nil
+}
+
+func (orderServer) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.Order, error) {
+	order, err := findOrder(db.WithContext(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return orderToProto(order), nil
+}
+
+func (orderServer) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	menuID := ""
+	if req.GetMenuId() != 0 {
+		menuID = strconv.FormatUint(uint64(req.GetMenuId()), 10)
+	}
+	orders, err := listOrders(db.WithContext(ctx), menuID, req.GetOpen())
+	if err != nil {
+		return nil, err
+	}
+	response := &orderv1.ListOrdersResponse{Orders: make([]*orderv1.Order, 0, len(orders))}
+	for _, order := range orders {
+		response.Orders = append(response.Orders, orderToProto(order))
+	}
+	return response, nil
+}
+
+func (orderServer) UpdateOrderStatus(ctx context.Context, req *orderv1.UpdateOrderStatusRequest) (*orderv1.Order, error) {
+	order, err := findOrder(db.WithContext(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(order, req.GetVersion()); err != nil {
+		return nil, err
+	}
+	if err := changeOrderStatus(ctx, &order, req.GetStatus(), req.GetChangedBy()); err != nil {
+		return nil, err
+	}
+	return orderToProto(order), nil
+}
+
+func (orderServer) CancelOrder(ctx context.Context, req *orderv1.CancelOrderRequest) (*orderv1.CancelOrderResponse, error) {
+	order, err := findOrder(db.WithContext(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(order, req.GetVersion()); err != nil {
+		return nil, err
+	}
+	stockReturned, err := applyCancel(ctx, &order, req.GetReason(), req.GetCancelledBy(), req.GetCooked())
+	if err != nil {
+		return nil, err
+	}
+	return &orderv1.CancelOrderResponse{Order: orderToProto(order), StockReturned: stockReturned}, nil
+}
+
+// Заказ в формате protobuf
+func orderToProto(order Order) *orderv1.Order {
+	modifiers := make([]*orderv1.OrderModifier, 0, len(order.Modifiers))
+	for _, modifier := range order.Modifiers {
+		modifiers = append(modifiers, &orderv1.OrderModifier{
+			Id:         uint32(modifier.ID),
+			ModifierId: uint32(modifier.ModifierID),
+			Name:       modifier.Name,
+			Type:       modifier.Type,
+			PriceDelta: modifier.PriceDelta,
+		})
+	}
+	result := &orderv1.Order{
+		Id:           uint32(order.ID),
+		OrderNumber:  order.OrderNumber,
+		MenuId:       uint32(order.MenuID),
+		Quantity:     int32(order.Quantity),
+		TableId:      uint32(order.TableID),
+		Status:       order.Status,
+		Notes:        order.Notes,
+		Modifiers:    modifiers,
+		UnitPrice:    order.UnitPrice,
+		TotalPrice:   order.TotalPrice,
+		Version:      int32(order.Version),
+		CancelReason: order.CancelReason,
+		CancelledBy:  order.CancelledBy,
+		Wasted:       order.Wasted,
+	}
+	if order.CancelledAt != nil {
+		result.CancelledAt = timestamppb.New(*order.CancelledAt)
+	}
+	return result
+}
+
+// Состояние по протоколу grpc.health.v1
+var grpcHealth = grpchealth.NewServer()
+
+// NewGRPCServer — gRPC-сервер с тем же каталогом ошибок, что и REST
+func NewGRPCServer() *grpc.Server {
+	server := grpc.NewServer(
+		tracing.ServerHandler(),
+		grpc.ChainUnaryInterceptor(
+			logging.UnaryServerInterceptor(),
+			apierr.UnaryServerInterceptor(messages),
+		),
+	)
+	orderv1.RegisterOrderServiceServer(server, orderServer{})
+	grpcHealth.SetServingStatus(orderv1.OrderService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, grpcHealth)
+	return server
+}
@@ -0,0 +1,70 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Запись истории изменений заказа
+type OrderHistory struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	OrderID   uint      `gorm:"index" json:"order_id"`
+	Action    string    `json:"action"`                    // modified, status, cancelled
+	Changes   string    `gorm:"type:jsonb" json:"changes"` // {"поле": {"from": ..., "to": ...}}
+	ChangedBy string    `json:"changed_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Действия, попадающие в историю
+const (
+	historyActionModified  = "modified"
+	historyActionStatus    = "status"
+	historyActionCancelled = "cancelled"
+)
+
+// Изменение одного поля
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Сохранение записи в историю заказа
+func recordHistory(tx *gorm.DB, orderID uint, action string, changes map[string]fieldChange, changedBy string) error {
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&OrderHistory{
+		OrderID:   orderID,
+		Action:    action,
+		Changes:   string(changesJSON),
+		ChangedBy: changedBy,
+	}).Error
+}
+
+// Получение истории изменений заказа
+func getOrderHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	var order Order
+
+	if err := requestDB(c).Unscoped().First(&order, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "order_not_found"))
+		return
+	}
+
+	var history []OrderHistory
+	if err := requestDB(c).Where("order_id = ?", order.ID).Order("created_at, id").Find(&history).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
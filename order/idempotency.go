@@ -0,0 +1,107 @@
+package order
+
+import (
+	"bytes"
+	"c_keeper_go/apierr"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Сохранённый ответ на запрос с заголовком Idempotency-Key
+type IdempotencyKey struct {
+	Key         string `gorm:"primaryKey;size:255"`
+	RequestHash string `gorm:"size:64"` // sha256 от метода, пути и тела запроса
+	StatusCode  int
+	Response    []byte
+	Completed   bool // false, пока первый запрос ещё обрабатывается
+	CreatedAt   time.Time
+}
+
+// Сколько хранится ответ для повторов
+var idempotencyWindow = time.Duration(getEnvInt("IDEMPOTENCY_WINDOW_HOURS", 24)) * time.Hour
+
+// Запоминает ответ в дополнение к отправке клиенту
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// Middleware для POST-запросов: повтор с тем же Idempotency-Key получает
+// первый ответ, а не создаёт заказ заново
+func idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			apierr.Abort(c, apierr.BadRequest("invalid_body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		// Устаревшие ключи можно использовать заново
+		requestDB(c).Where("key = ? AND created_at < ?", key, time.Now().Add(-idempotencyWindow)).Delete(&IdempotencyKey{})
+
+		record := IdempotencyKey{Key: key, RequestHash: requestHash}
+		result := requestDB(c).Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			apierr.Abort(c, apierr.Internal(result.Error))
+			return
+		}
+
+		// Ключ уже занят — отвечаем сохранённым результатом
+		if result.RowsAffected == 0 {
+			var existing IdempotencyKey
+			if err := requestDB(c).First(&existing, "key = ?", key).Error; err != nil {
+				apierr.Abort(c, apierr.Internal(err))
+				return
+			}
+			switch {
+			case existing.RequestHash != requestHash:
+				apierr.Abort(c, apierr.Unprocessable("idempotency_key_reused"))
+			case !existing.Completed:
+				apierr.Abort(c, apierr.Conflict("idempotency_key_in_usage"))
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.StatusCode, "application/json; charset=utf-8", existing.Response)
+				c.Abort()
+			}
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// Ошибки отрисовывает apierr.Middleware уже после нас, поэтому их не сохраняем:
+		// после неудачного запроса клиент может повторить его с тем же ключом
+		if len(c.Errors) > 0 || writer.Status() >= http.StatusInternalServerError {
+			requestDB(c).Delete(&IdempotencyKey{}, "key = ?", key)
+			return
+		}
+		requestDB(c).Model(&IdempotencyKey{}).Where("key = ?", key).Updates(map[string]interface{}{
+			"status_code": writer.Status(),
+			"response":    writer.body.Bytes(),
+			"completed":   true,
+		})
+	}
+}
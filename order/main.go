@@ -1,86 +1,293 @@
-package main
+package order
 
 import (
-	"encoding/json"
-	"errors"
+	"c_keeper_go/apierr"
+	"c_keeper_go/health"
+	"c_keeper_go/logging"
+	"c_keeper_go/metrics"
+	menuv1 "c_keeper_go/proto/menu/v1"
+	"c_keeper_go/tracing"
+	"context"
 	"fmt"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 )
 
 // Структура для заказа
 type Order struct {
-	ID          uint   `gorm:"primaryKey"`
-	OrderNumber uint   `json:"order_number"` // Номер заказа
-	MenuID      uint   `json:"menu_id"`
-	Quantity    int    `json:"quantity"`
-	TableID     uint   `json:"table_id"`
-	Status      string `json:"status"`
+	ID          uint            `gorm:"primaryKey"`
+	OrderNumber string          `gorm:"uniqueIndex;size:64" json:"order_number"` // Номер заказа, назначается сервером
+	MenuID      uint            `json:"menu_id"`
+	Quantity    int             `json:"quantity"`
+	TableID     uint            `json:"table_id"`
+	Status      string          `json:"status"`
+	Notes       string          `json:"notes"`                               // пожелания гостя
+	ModifierIDs []uint          `gorm:"-" json:"modifier_ids,omitempty"`     // выбранные модификаторы (во входящем запросе)
+	Modifiers   []OrderModifier `gorm:"foreignKey:OrderID" json:"modifiers"` // модификаторы на момент заказа
+	UnitPrice   float64         `json:"unit_price"`                          // цена порции с модификаторами
+	TotalPrice  float64         `json:"total_price"`                         // UnitPrice * Quantity
+	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"deleted_at"`             // мягкое удаление
+	Version     int             `gorm:"not null;default:1" json:"version"`   // для оптимистичной блокировки
+	CreatedAt   time.Time       `json:"created_at"`
+	ShiftID     *uint           `gorm:"index" json:"shift_id"`   // смена, в которую принят заказ
+	PaymentID   *uint           `gorm:"index" json:"payment_id"` // оплата, закрывшая заказ
+
+	// Данные об отмене заказа
+	CancelReason string     `json:"cancel_reason,omitempty"`
+	CancelledBy  string     `json:"cancelled_by,omitempty"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	Wasted       bool       `json:"wasted"` // блюдо уже приготовлено и списано
+
+	// Порции списаны в menu, когда кухня начала готовить (ConsumeStock)
+	StockConsumed bool `gorm:"not null;default:false" json:"-"`
 }
 
+// Статусы заказа
+const (
+	statusPending    = "В ожидании"
+	statusInProgress = "В процессе"
+	statusCompleted  = "Завершен"
+	statusCancelled  = "Отменен"
+)
+
+// Закрытые заказы не считаются открытыми и не меняют статус
+var closedStatuses = []string{statusCompleted, statusCancelled}
+
 var db *gorm.DB
 
-// Инициализация базы данных
-func initDB() {
-	var err error
-	dsn := "user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("ошибка при подключении к базе данных: %v", err)
+// Пробы готовности; зависимости добавляет Setup
+var readiness = health.New()
+
+// Менеджер определяется по заголовку X-User-Role
+func isManager(c *gin.Context) bool {
+	return c.GetHeader("X-User-Role") == "manager"
+}
+
+// База в контексте запроса: запросы к ней попадают в трейс запроса
+func requestDB(c *gin.Context) *gorm.DB {
+	return db.WithContext(c.Request.Context())
+}
+
+// Запрос с учётом удалённых заказов (?include_deleted=true, только для менеджеров)
+func scopedDB(c *gin.Context) *gorm.DB {
+	if c.Query("include_deleted") == "true" && isManager(c) {
+		return requestDB(c).Unscoped()
+	}
+	return requestDB(c)
+}
+
+// Значение переменной окружения или значение по умолчанию
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
 	}
-	db.AutoMigrate(&Order{})
+	return fallback
 }
 
-func fetchDishDetails(menuID uint) (map[string]interface{}, error) {
-	url := fmt.Sprintf("http://localhost:5003/menu/%d", menuID)
-	resp, err := http.Get(url)
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
 	if err != nil {
-		return nil, fmt.Errorf("ошибка соединения с menu: %v", err)
+		return fallback
 	}
-	defer resp.Body.Close()
+	return value
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("menu вернул статус: %d", resp.StatusCode)
+// Config — настройки запуска сервиса
+type Config struct {
+	DatabaseDSN     string
+	MenuGRPCAddr    string
+	HTTPAddr        string
+	GRPCAddr        string
+	ShutdownTimeout time.Duration // сколько ждать начатые запросы при остановке
+}
+
+// ConfigFromEnv читает настройки из переменных окружения, как в Docker Compose
+func ConfigFromEnv() Config {
+	return Config{
+		DatabaseDSN:     getEnv("DATABASE_DSN", "user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"),
+		MenuGRPCAddr:    getEnv("MENU_GRPC_ADDR", "localhost:6003"),
+		HTTPAddr:        ":" + getEnv("ORDER_HTTP_PORT", "5004"), // по умолчанию порт 5004
+		GRPCAddr:        ":" + getEnv("ORDER_GRPC_PORT", "6004"),
+		ShutdownTimeout: time.Duration(getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 15)) * time.Second,
+	}
+}
+
+// Setup подключает сервис к базе и сервису menu; вызывается до NewRouter и NewGRPCServer
+func Setup(config Config) error {
+	if err := openDatabase(config.DatabaseDSN); err != nil {
+		return err
+	}
+	if err := connectMenu(config.MenuGRPCAddr); err != nil {
+		return err
+	}
+	readiness = health.New()
+	readiness.Add("postgres", health.DB(db))
+	readiness.Add("menu", health.GRPC(menuConn, menuv1.MenuService_ServiceDesc.ServiceName))
+	return nil
+}
+
+// Shutdown переводит сервис в «не готов» перед остановкой серверов
+func Shutdown() {
+	readiness.Shutdown()
+	grpcHealth.Shutdown()
+}
+
+// Close закрывает соединения с базой и сервисом menu после остановки серверов
+func Close() {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	menuConn.Close()
+}
+
+// Инициализация базы данных
+func openDatabase(dsn string) error {
+	var err error
+	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("подключение к базе данных: %w", err)
+	}
+	if err := configurePool(db); err != nil {
+		return fmt.Errorf("настройка пула соединений: %w", err)
+	}
+	if err := metrics.InstrumentGORM(db); err != nil {
+		return fmt.Errorf("подключение метрик базы данных: %w", err)
+	}
+	if err := tracing.InstrumentGORM(db); err != nil {
+		return fmt.Errorf("подключение трейсинга базы данных: %w", err)
+	}
+	if err := migrateOrderNumbers(db); err != nil {
+		return fmt.Errorf("миграция номеров заказов: %w", err)
+	}
+	if err := db.AutoMigrate(&Order{}, &OrderModifier{}, &OrderHistory{}, &OrderCounter{}, &IdempotencyKey{}); err != nil {
+		return fmt.Errorf("миграция заказов: %w", err)
+	}
+	if err := migrateShifts(db); err != nil {
+		return fmt.Errorf("миграция смен: %w", err)
 	}
+	if err := db.AutoMigrate(&Table{}, &Reservation{}); err != nil {
+		return fmt.Errorf("миграция столов и броней: %w", err)
+	}
+	return nil
+}
 
-	var menuItem map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&menuItem); err != nil {
-		return nil, fmt.Errorf("ошибка декодирования ответа: %v", err)
+// Размер пула соединений с базой (DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME_MINUTES)
+func configurePool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
 	}
+	sqlDB.SetMaxOpenConns(getEnvInt("DB_MAX_OPEN_CONNS", 25))
+	sqlDB.SetMaxIdleConns(getEnvInt("DB_MAX_IDLE_CONNS", 10))
+	sqlDB.SetConnMaxLifetime(time.Duration(getEnvInt("DB_CONN_MAX_LIFETIME_MINUTES", 30)) * time.Minute)
+	return nil
+}
 
-	return menuItem, nil
+// Тело запроса POST /order: только поля, которые задаёт клиент;
+// номер, статус, цены, смена и служебные поля назначает сервер
+type orderInput struct {
+	OrderNumber string `json:"order_number"` // принимается только чтобы отклонить запрос с номером
+	MenuID      uint   `json:"menu_id"`
+	Quantity    int    `json:"quantity"`
+	TableID     uint   `json:"table_id"`
+	Notes       string `json:"notes"`
+	ModifierIDs []uint `json:"modifier_ids"`
 }
 
 // Создание заказа
 func createOrder(c *gin.Context) {
-	var order Order
-	if err := c.ShouldBindJSON(&order); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var input orderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
 		return
 	}
 
-	// Сохраняем заказ в базу данных
-	order.Status = "В процессе"
-	if err := db.Create(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if input.OrderNumber != "" {
+		apierr.Abort(c, apierr.BadRequest("order_number_assigned"))
+		return
+	}
+
+	order := Order{
+		MenuID:      input.MenuID,
+		Quantity:    input.Quantity,
+		TableID:     input.TableID,
+		Notes:       input.Notes,
+		ModifierIDs: input.ModifierIDs,
+	}
+	if err := placeOrder(c.Request.Context(), &order); err != nil {
+		apierr.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Заказ успешно создан",
+		"message": successMessage(c, "order_created"),
 		"order":   order,
 	})
 }
 
-// Получение всех заказов
-func getOrders(c *gin.Context) {
+// Проверка, расчёт цены, резерв порций и сохранение нового заказа; общая логика для REST и gRPC
+func placeOrder(ctx context.Context, order *Order) error {
+	if errs := order.validate(); len(errs) > 0 {
+		return apierr.Validation(errs)
+	}
+
+	// Проверяем модификаторы и считаем цену по данным меню
+	if err := priceOrder(ctx, order, true); err != nil {
+		return err
+	}
+
+	// Резервируем порции в сервисе menu
+	if err := adjustDishStock(ctx, order.MenuID, -order.Quantity); err != nil {
+		return err
+	}
+
+	// Сохраняем заказ в базу данных; смену назначает BeforeCreate
+	order.Status = statusPending
+	order.ShiftID, order.PaymentID = nil, nil
+	if err := db.WithContext(ctx).Create(order).Error; err != nil {
+		adjustDishStock(context.WithoutCancel(ctx), order.MenuID, order.Quantity)
+		return apierr.Internal(err)
+	}
+	ordersCreated.Inc()
+	return nil
+}
+
+// Заказы с модификаторами; menuID == "" — по всем блюдам, open — только незакрытые
+func listOrders(query *gorm.DB, menuID string, open bool) ([]Order, error) {
+	if menuID != "" {
+		query = query.Where("menu_id = ?", menuID)
+	}
+	if open {
+		query = query.Where("status NOT IN ?", closedStatuses)
+	}
+
 	var orders []Order
-	if err := db.Find(&orders).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := query.Preload("Modifiers").Find(&orders).Error; err != nil {
+		return nil, apierr.Internal(err)
+	}
+	return orders, nil
+}
+
+// Заказ с модификаторами
+func findOrder(query *gorm.DB, id interface{}) (Order, error) {
+	var order Order
+	if err := query.Preload("Modifiers").First(&order, id).Error; err != nil {
+		return order, dbError(err, "order_not_found")
+	}
+	return order, nil
+}
+
+// Получение всех заказов (фильтры: ?menu_id=, ?open=true)
+func getOrders(c *gin.Context) {
+	orders, err := listOrders(scopedDB(c), c.Query("menu_id"), c.Query("open") == "true")
+	if err != nil {
+		apierr.Abort(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, orders)
@@ -88,97 +295,175 @@ func getOrders(c *gin.Context) {
 
 // Получение конкретного заказа по ID
 func getOrder(c *gin.Context) {
-	orderID := c.Param("id")
-	var order Order
-	if err := db.First(&order, orderID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Заказ не найден"})
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	order, err := findOrder(scopedDB(c), id)
+	if err != nil {
+		apierr.Abort(c, err)
 		return
 	}
+	c.Header("ETag", orderETag(order))
 	c.JSON(http.StatusOK, order)
 }
 
 func UpdateOrderStatus(c *gin.Context) {
 	var order Order
-	id := c.Param("id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
 
-	if err := db.First(&order, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Заказ не найден"})
+	if err := requestDB(c).First(&order, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "order_not_found"))
+		return
+	}
+
+	if !checkIfMatch(c, order) {
 		return
 	}
 
 	var statusUpdate struct {
-		Status string `json:"status"`
+		Status    string `json:"status"`
+		ChangedBy string `json:"changed_by"`
 	}
 
 	if err := c.ShouldBindJSON(&statusUpdate); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные для обновления"})
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
 		return
 	}
 
-	validStatuses := map[string]bool{"В процессе": true, "Завершен": true}
-	if !validStatuses[statusUpdate.Status] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный статус"})
+	if err := changeOrderStatus(c.Request.Context(), &order, statusUpdate.Status, statusUpdate.ChangedBy); err != nil {
+		apierr.Abort(c, err)
 		return
 	}
 
-	order.Status = statusUpdate.Status
-	if err := db.Save(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при обновлении заказа"})
-		return
+	c.Header("ETag", orderETag(order))
+	c.JSON(http.StatusOK, gin.H{"message": successMessage(c, "order_status_updated"), "order": order})
+}
+
+// Смена статуса с записью в историю; общая логика для REST и gRPC
+func changeOrderStatus(ctx context.Context, order *Order, newStatus, changedBy string) error {
+	if order.Status == statusCancelled {
+		return apierr.Conflict("order_cancelled")
+	}
+	if err := checkNotPaid(*order); err != nil {
+		return err
+	}
+
+	validStatuses := map[string]bool{statusPending: true, statusInProgress: true, statusCompleted: true}
+	if !validStatuses[newStatus] {
+		return apierr.BadRequest("invalid_status")
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Статус заказа обновлен", "order": order})
+	changes := map[string]fieldChange{"status": {From: order.Status, To: newStatus}}
+	previous := order.Status
+
+	// Кухня взяла заказ в работу: резерв порций в menu становится расходом ингредиентов
+	consume := previous == statusPending && newStatus != statusPending && !order.StockConsumed
+	if consume {
+		if err := consumeDishStock(ctx, order.MenuID, order.Quantity); err != nil {
+			return err
+		}
+		order.StockConsumed = true
+	}
+
+	order.Status = newStatus
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := saveOrderVersioned(tx, order); err != nil {
+			return err
+		}
+		return recordHistory(tx, order.ID, historyActionStatus, changes, changedBy)
+	})
+	if err != nil {
+		order.Status = previous
+		if consume {
+			order.StockConsumed = false
+			consumeDishStock(context.WithoutCancel(ctx), order.MenuID, -order.Quantity)
+		}
+		return err
+	}
+	if newStatus == statusCompleted && previous != statusCompleted {
+		observeCompleted(*order)
+	}
+	return nil
 }
 
 func deleteOrder(c *gin.Context) {
-	id := c.Param("id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
 	var order Order
 
-	if err := db.First(&order, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Заказ не найден"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении заказа"})
+	if err := requestDB(c).First(&order, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "order_not_found"))
+		return
+	}
+
+	if err := checkNotPaid(order); err != nil {
+		apierr.Abort(c, err)
 		return
 	}
 
-	if err := db.Delete(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении заказа"})
+	if err := requestDB(c).Delete(&order).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Заказ успешно удалён"})
+	c.JSON(http.StatusOK, gin.H{"message": successMessage(c, "order_deleted")})
 }
 
-// Получение описания блюда по ID заказа
-func getDishDescriptionByOrderID(c *gin.Context) {
-	// Извлекаем ID заказа из параметров запроса
-	orderID := c.Param("id")
+// Восстановление мягко удалённого заказа
+func restoreOrder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
 	var order Order
-	if err := db.First(&order, orderID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Заказ не найден"})
+
+	if err := requestDB(c).Unscoped().First(&order, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "order_not_found"))
 		return
 	}
 
-	// Получаем данные блюда из сервиса menu
-	menuItem, err := fetchDishDetails(order.MenuID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Не удалось получить данные о блюде: %v", err)})
+	if !order.DeletedAt.Valid {
+		apierr.Abort(c, apierr.Conflict("order_not_deleted"))
 		return
 	}
 
-	// Извлекаем описание блюда
-	description, ok := menuItem["description"].(string)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось извлечь описание блюда"})
+	if err := requestDB(c).Unscoped().Model(&order).Update("deleted_at", nil).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
 		return
 	}
+	order.DeletedAt = gorm.DeletedAt{}
+
+	c.JSON(http.StatusOK, gin.H{"message": successMessage(c, "order_restored"), "order": order})
+}
 
-	// Извлекаем описание блюда
-	price, ok := menuItem["price"].(float64)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось извлечь цену блюда"})
+// Получение описания блюда по ID заказа
+func getDishDescriptionByOrderID(c *gin.Context) {
+	// Извлекаем ID заказа из параметров запроса
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	var order Order
+	if err := requestDB(c).First(&order, orderID).Error; err != nil {
+		apierr.Abort(c, dbError(err, "order_not_found"))
+		return
+	}
+
+	// Получаем данные блюда из сервиса menu
+	dish, err := fetchDish(c.Request.Context(), order.MenuID)
+	if err != nil {
+		apierr.Abort(c, err)
 		return
 	}
 
@@ -186,26 +471,64 @@ func getDishDescriptionByOrderID(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"order_id":    order.ID,
 		"menu_id":     order.MenuID,
-		"description": description,
-		"price":       price,
+		"description": dish.GetDescription(),
+		"price":       dish.GetPrice(),
 	})
 }
 
-func main() {
-	initDB()
-
-	r := gin.Default()
+// NewRouter — HTTP API сервиса со всеми маршрутами
+func NewRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(tracing.Middleware("order"))
+	r.Use(logging.Middleware())
+	r.Use(metrics.Middleware())
 	r.Use(cors.Default())
+	r.Use(apierr.Middleware(messages))
 
 	// CRUD-операции для заказов
-	r.POST("/order", createOrder)
+	r.POST("/order", idempotent(), createOrder)
 
 	r.GET("/order/:id/description", getDishDescriptionByOrderID)
 
 	r.GET("/orders", getOrders)
 	r.GET("/order/:id", getOrder)
 	r.PUT("/order/:id/status", UpdateOrderStatus)
+	r.PATCH("/order/:id", modifyOrder)
+	r.GET("/order/:id/history", getOrderHistory)
+	r.GET("/order/:id/ticket", getKitchenTicket)
 	r.DELETE("/order/:id", deleteOrder)
-
-	r.Run(":5004") // сервис будет доступен на порту 5004
+	r.POST("/order/:id/restore", restoreOrder)
+	r.POST("/order/:id/cancel", cancelOrder)
+
+	// Кассовые смены и оплата счетов
+	r.POST("/shifts", openShift)
+	r.GET("/shifts", getShifts)
+	r.GET("/shifts/current", getCurrentShift)
+	r.POST("/shifts/current/close", closeShift)
+	r.GET("/shifts/:id", getShift)
+	r.POST("/payments", idempotent(), createPayment)
+
+	// Столы и бронирование
+	r.POST("/tables", createTable)
+	r.GET("/tables", getTables)
+	r.POST("/tables/:id/release", releaseTable)
+	r.POST("/reservations", createReservation)
+	r.GET("/reservations", getReservations)
+	r.POST("/reservations/:id/arrive", arriveReservation)
+	r.POST("/reservations/:id/no-show", noShowReservation)
+	r.POST("/reservations/:id/cancel", cancelReservation)
+
+	// Отчёты для менеджера
+	r.GET("/reports/revenue", getRevenueReport)
+	r.GET("/reports/dishes", getDishReport)
+	r.GET("/reports/categories", getCategoryReport)
+	r.GET("/reports/average-check", getAverageCheckReport)
+	r.GET("/reports/tables", getTableTurnoverReport)
+
+	r.GET("/openapi.json", getOpenAPISpec)
+	r.GET("/metrics", metrics.Handler())
+	r.GET("/healthz", readiness.Liveness)
+	r.GET("/readyz", readiness.Readiness)
+	return r
 }
@@ -1,16 +1,34 @@
-package main
+package order
 
 import (
 	"bytes"
+	"c_keeper_go/apierr"
+	menuv1 "c_keeper_go/proto/menu/v1"
+	orderv1 "c_keeper_go/proto/order/v1"
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Устанавливаем тестовую базу данных (в памяти)
@@ -21,29 +39,229 @@ func initTestDB() *gorm.DB {
 		panic("ошибка при подключении к базе данных для теста")
 	}
 	// Создаем таблицу для заказов
-	db.AutoMigrate(&Order{})
+	migrateOrderNumbers(db)
+	db.AutoMigrate(&Order{}, &OrderModifier{}, &OrderHistory{}, &OrderCounter{}, &IdempotencyKey{})
+	migrateShifts(db)
+	db.AutoMigrate(&Table{}, &Reservation{})
+	// Без поддельного menu вызовы уходят на адрес по умолчанию и получают menu_unavailable
+	if menuClient == nil {
+		connectMenu("localhost:6003")
+	}
 	return db
 }
 
+// Маршрутизатор по спецификации openapi.json для проверки запросов и ответов в тестах
+var specRouter = func() routers.Router {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		panic("ошибка разбора openapi.json: " + err.Error())
+	}
+	// Тестовые запросы идут без хоста, поэтому адреса серверов не учитываем
+	doc.Servers = nil
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		panic("ошибка построения маршрутов openapi.json: " + err.Error())
+	}
+	return router
+}()
+
+// Проверка запроса по спецификации; тело запроса остаётся доступным для обработчика
+func validateRequest(t *testing.T, req *http.Request) {
+	t.Helper()
+	route, pathParams, err := specRouter.FindRoute(req)
+	if !assert.NoError(t, err, "маршрут отсутствует в openapi.json") {
+		return
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	assert.NoError(t, openapi3filter.ValidateRequest(context.Background(), input), "запрос не соответствует openapi.json")
+	req.Body = io.NopCloser(bytes.NewReader(body))
+}
+
+// Проверка ответа по спецификации
+func validateResponse(t *testing.T, req *http.Request, w *httptest.ResponseRecorder) {
+	t.Helper()
+	route, pathParams, err := specRouter.FindRoute(req)
+	if !assert.NoError(t, err, "маршрут отсутствует в openapi.json") {
+		return
+	}
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status:  w.Code,
+		Header:  w.Header(),
+		Body:    io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+		Options: &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	assert.NoError(t, openapi3filter.ValidateResponse(context.Background(), input), "ответ не соответствует openapi.json")
+}
+
+// Выполнение запроса с проверкой запроса и ответа по спецификации
+func serveWithSpec(t *testing.T, router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	validateRequest(t, req)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	validateResponse(t, req, w)
+	return w
+}
+
+// Роутер с тем же центральным обработчиком ошибок, что и в main
+func newTestRouter() *gin.Engine {
+	r := gin.Default()
+	r.Use(apierr.Middleware(messages))
+	return r
+}
+
+// Поддельный сервис menu: хранит остатки блюд и отвечает по gRPC как настоящий
+type fakeMenu struct {
+	menuv1.UnimplementedMenuServiceServer
+	mu       sync.Mutex
+	stock    map[uint]int
+	consumed map[uint]int  // порции, списанные кухней
+	closed   map[uint]bool // блюда вне расписания
+	deleted  map[uint]bool // удалённые блюда: видны только в ListDishes с include_deleted
+}
+
+// Все блюда стоят 100 и имеют одинаковый набор модификаторов
+func (f *fakeMenu) GetDish(ctx context.Context, req *menuv1.GetDishRequest) (*menuv1.Dish, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := uint(req.GetId())
+	if _, ok := f.stock[id]; !ok {
+		return nil, status.Error(codes.NotFound, "блюдо не найдено")
+	}
+	return &menuv1.Dish{
+		Id:                req.GetId(),
+		Name:              fmt.Sprintf("Блюдо %d", id),
+		Price:             100.0,
+		Description:       "Тестовое блюдо",
+		AvailableQuantity: int32(f.stock[id]),
+		OutsideSchedule:   f.closed[id],
+		Modifiers: []*menuv1.Modifier{
+			{Id: 1, Name: "Большая порция", Type: "size", PriceDelta: 50.0},
+			{Id: 2, Name: "Маленькая порция", Type: "size", PriceDelta: -20.0},
+			{Id: 3, Name: "Без сметаны", Type: "removal", PriceDelta: 0.0},
+		},
+	}, nil
+}
+
+// Блюда 1 и 2 — супы, остальные — горячее
+func (f *fakeMenu) ListDishes(ctx context.Context, req *menuv1.ListDishesRequest) (*menuv1.ListDishesResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &menuv1.ListDishesResponse{}
+	for id := range f.stock {
+		if f.deleted[id] && !req.GetIncludeDeleted() {
+			continue
+		}
+		category := &menuv1.Category{Id: 2, Name: "Горячее"}
+		if id <= 2 {
+			category = &menuv1.Category{Id: 1, Name: "Супы"}
+		}
+		resp.Dishes = append(resp.Dishes, &menuv1.Dish{
+			Id:         uint32(id),
+			Name:       fmt.Sprintf("Блюдо %d", id),
+			CategoryId: category.Id,
+			Category:   category,
+		})
+	}
+	return resp, nil
+}
+
+func (f *fakeMenu) AdjustStock(ctx context.Context, req *menuv1.AdjustStockRequest) (*menuv1.Dish, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := uint(req.GetId())
+	if _, ok := f.stock[id]; !ok {
+		return nil, status.Error(codes.NotFound, "блюдо не найдено")
+	}
+	if f.stock[id]+int(req.GetDelta()) < 0 {
+		return nil, status.Error(codes.FailedPrecondition, "недостаточно порций")
+	}
+	f.stock[id] += int(req.GetDelta())
+	if req.GetConsumed() {
+		f.consumed[id] -= int(req.GetDelta())
+	}
+	return &menuv1.Dish{Id: req.GetId(), AvailableQuantity: int32(f.stock[id])}, nil
+}
+
+// Списание не меняет доступные порции: они уже зарезервированы заказом
+func (f *fakeMenu) ConsumeStock(ctx context.Context, req *menuv1.ConsumeStockRequest) (*menuv1.Dish, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := uint(req.GetId())
+	if _, ok := f.stock[id]; !ok {
+		return nil, status.Error(codes.NotFound, "блюдо не найдено")
+	}
+	f.consumed[id] += int(req.GetQuantity())
+	return &menuv1.Dish{Id: req.GetId(), AvailableQuantity: int32(f.stock[id])}, nil
+}
+
+// Запуск gRPC-сервера в памяти и соединение с ним
+func serveInMemory(t *testing.T, server *grpc.Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// Запуск поддельного menu; возвращает карту остатков, которую он меняет
+func startFakeMenu(t *testing.T, stock map[uint]int) map[uint]int {
+	return startFakeMenuServer(t, &fakeMenu{stock: stock, consumed: map[uint]int{}}).stock
+}
+
+func startFakeMenuServer(t *testing.T, menu *fakeMenu) *fakeMenu {
+	server := grpc.NewServer()
+	menuv1.RegisterMenuServiceServer(server, menu)
+
+	previous := menuClient
+	menuClient = menuv1.NewMenuServiceClient(serveInMemory(t, server))
+	t.Cleanup(func() { menuClient = previous })
+	return menu
+}
+
 // Тестирование создания заказа
 func TestCreateOrder(t *testing.T) {
 	db = initTestDB()
-	r := gin.Default()
+	startFakeMenu(t, map[uint]int{1: 10})
+	r := newTestRouter()
 	r.POST("/order", createOrder)
 
 	order := Order{
-		OrderNumber: 1,
-		MenuID:      1,
-		Quantity:    2,
-		TableID:     1,
+		MenuID:   1,
+		Quantity: 2,
+		TableID:  1,
 	}
 
 	orderJSON, _ := json.Marshal(order)
 	req, _ := http.NewRequest("POST", "/order", bytes.NewBuffer(orderJSON))
 	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
-
-	r.ServeHTTP(w, req)
+	w := serveWithSpec(t, r, req)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 
@@ -57,13 +275,11 @@ func TestCreateOrder(t *testing.T) {
 // Тестирование получения всех заказов
 func TestGetOrders(t *testing.T) {
 	db = initTestDB()
-	r := gin.Default()
+	r := newTestRouter()
 	r.GET("/orders", getOrders)
 
 	req, _ := http.NewRequest("GET", "/orders", nil)
-	w := httptest.NewRecorder()
-
-	r.ServeHTTP(w, req)
+	w := serveWithSpec(t, r, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
@@ -76,22 +292,19 @@ func TestGetOrders(t *testing.T) {
 // Тестирование получения заказа по ID
 func TestGetOrder(t *testing.T) {
 	db = initTestDB()
-	r := gin.Default()
+	r := newTestRouter()
 	r.GET("/order/:id", getOrder)
 
 	order := Order{
-		OrderNumber: 1,
-		MenuID:      1,
-		Quantity:    2,
-		TableID:     1,
-		Status:      "В процессе",
+		MenuID:   1,
+		Quantity: 2,
+		TableID:  1,
+		Status:   "В процессе",
 	}
 	db.Create(&order)
 
 	req, _ := http.NewRequest("GET", fmt.Sprintf("/order/%d", order.ID), nil)
-	w := httptest.NewRecorder()
-
-	r.ServeHTTP(w, req)
+	w := serveWithSpec(t, r, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
@@ -104,25 +317,23 @@ func TestGetOrder(t *testing.T) {
 // Тестирование получения заказа по несуществующему ID
 func TestGetOrderNotFound(t *testing.T) {
 	db = initTestDB()
-	r := gin.Default()
+	r := newTestRouter()
 	r.GET("/order/:id", getOrder)
 
 	req, _ := http.NewRequest("GET", "/order/999", nil)
-	w := httptest.NewRecorder()
-
-	r.ServeHTTP(w, req)
+	w := serveWithSpec(t, r, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
 
 	var response map[string]interface{}
 	json.Unmarshal(w.Body.Bytes(), &response)
 
-	assert.Equal(t, "Заказ не найден", response["error"])
+	assert.Equal(t, "Заказ не найден", response["error"].(map[string]interface{})["message"])
 }
 
 func TestDeleteOrder(t *testing.T) {
 	db = initTestDB()
-	r := gin.Default()
+	r := newTestRouter()
 	r.DELETE("/order/:id", deleteOrder)
 
 	// Создаем новый заказ
@@ -148,15 +359,14 @@ func TestDeleteOrder(t *testing.T) {
 // Тестирование обновления статуса заказа
 func TestUpdateOrderStatus(t *testing.T) {
 	db = initTestDB()
-	r := gin.Default()
+	r := newTestRouter()
 	r.PUT("/order/:id/status", UpdateOrderStatus)
 
 	order := Order{
-		OrderNumber: 1,
-		MenuID:      1,
-		Quantity:    2,
-		TableID:     1,
-		Status:      "В процессе",
+		MenuID:   1,
+		Quantity: 2,
+		TableID:  1,
+		Status:   "В процессе",
 	}
 	db.Create(&order)
 
@@ -177,18 +387,35 @@ func TestUpdateOrderStatus(t *testing.T) {
 	assert.Equal(t, "Завершен", response["order"].(map[string]interface{})["status"])
 }
 
+// Сообщение об успехе на языке Accept-Language, как и ошибки
+func TestSuccessMessageLanguage(t *testing.T) {
+	db = initTestDB()
+	r := newTestRouter()
+	r.DELETE("/order/:id", deleteOrder)
+
+	order := Order{MenuID: 1, Quantity: 1, TableID: 1, Status: statusCompleted}
+	db.Create(&order)
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/order/%d", order.ID), nil)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message": "Order deleted"}`, w.Body.String())
+}
+
 func TestUpdateOrderStatusInvalid(t *testing.T) {
 	db = initTestDB()
-	r := gin.Default()
+	r := newTestRouter()
 	r.PUT("/order/:id/status", UpdateOrderStatus)
 
 	// Создаем заказ для теста
 	order := Order{
-		OrderNumber: 1,
-		MenuID:      1,
-		Quantity:    2,
-		TableID:     1,
-		Status:      "В процессе",
+		MenuID:   1,
+		Quantity: 2,
+		TableID:  1,
+		Status:   "В процессе",
 	}
 	db.Create(&order)
 
@@ -208,12 +435,12 @@ func TestUpdateOrderStatusInvalid(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &response)
 
 	// Проверяем сообщение об ошибке
-	assert.Equal(t, "Некорректный статус", response["error"])
+	assert.Equal(t, "Некорректный статус", response["error"].(map[string]interface{})["message"])
 }
 
 func TestDeleteOrderNotFound(t *testing.T) {
 	db = initTestDB()
-	r := gin.Default()
+	r := newTestRouter()
 	r.DELETE("/order/:id", deleteOrder)
 
 	// Отправляем DELETE-запрос для несуществующего заказа
@@ -229,14 +456,14 @@ func TestDeleteOrderNotFound(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &response)
 
 	// Проверяем сообщение об ошибке
-	assert.Equal(t, "Заказ не найден", response["error"])
+	assert.Equal(t, "Заказ не найден", response["error"].(map[string]interface{})["message"])
 }
 
 // Тест на случай, когда заказ не найден
 func TestGetDishDescriptionByOrderID_OrderNotFound(t *testing.T) {
 	// Инициализация тестовой базы данных
 	_ = initTestDB()
-	r := gin.Default()
+	r := newTestRouter()
 	r.GET("/order/:id/description", getDishDescriptionByOrderID)
 
 	// Отправка запроса для несуществующего заказа
@@ -249,14 +476,14 @@ func TestGetDishDescriptionByOrderID_OrderNotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 	var response map[string]interface{}
 	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Equal(t, "Заказ не найден", response["error"])
+	assert.Equal(t, "Заказ не найден", response["error"].(map[string]interface{})["message"])
 }
 
 func TestGetDishDescriptionByOrderID_MenuServiceError(t *testing.T) {
 	// Имитируем ошибку получения данных меню (например, заказ не найден)
 	req, _ := http.NewRequest("GET", "/order/999/description", nil)
 	rec := httptest.NewRecorder()
-	handler := gin.New()
+	handler := newTestRouter()
 	handler.GET("/order/:id/description", getDishDescriptionByOrderID)
 
 	handler.ServeHTTP(rec, req)
@@ -270,7 +497,7 @@ func TestGetDishDescriptionByOrderID_InvalidMenuData(t *testing.T) {
 	// Имитируем ошибку с пустыми данными блюда (например, заказ не найден)
 	req, _ := http.NewRequest("GET", "/order/999/description", nil)
 	rec := httptest.NewRecorder()
-	handler := gin.New()
+	handler := newTestRouter()
 	handler.GET("/order/:id/description", getDishDescriptionByOrderID)
 
 	// Обрабатываем запрос
@@ -282,25 +509,28 @@ func TestGetDishDescriptionByOrderID_InvalidMenuData(t *testing.T) {
 }
 
 func TestGetDishDescriptionByOrderID_Success(t *testing.T) {
-	// Создаем mock-запрос, который будет возвращать корректные данные
-	req, _ := http.NewRequest("GET", "/order/214/description", nil)
-	rec := httptest.NewRecorder()
-	handler := gin.New()
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 10})
+	order := Order{MenuID: 1, Quantity: 1, TableID: 1, Status: statusPending}
+	db.Create(&order)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/order/%d/description", order.ID), nil)
+	handler := newTestRouter()
 	handler.GET("/order/:id/description", getDishDescriptionByOrderID)
 
-	handler.ServeHTTP(rec, req)
+	rec := serveWithSpec(t, handler, req)
 
-	// Проверяем статус ответа и содержимое
+	// Описание и цена приходят из сервиса menu
 	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.Contains(t, rec.Body.String(), "Классический борщ с мясом и сметаной")
-	assert.Contains(t, rec.Body.String(), "120.5")
+	assert.Contains(t, rec.Body.String(), "Тестовое блюдо")
+	assert.Contains(t, rec.Body.String(), `"price":100`)
 }
 
 func TestGetDishDescriptionByOrderID_NotFound(t *testing.T) {
 	// Отправляем запрос с несуществующим order_id
 	req, _ := http.NewRequest("GET", "/order/999/description", nil)
 	rec := httptest.NewRecorder()
-	handler := gin.New()
+	handler := newTestRouter()
 	handler.GET("/order/:id/description", getDishDescriptionByOrderID)
 
 	handler.ServeHTTP(rec, req)
@@ -309,3 +539,908 @@ func TestGetDishDescriptionByOrderID_NotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, rec.Code)
 	assert.Contains(t, rec.Body.String(), "Заказ не найден")
 }
+
+func TestRestoreOrder(t *testing.T) {
+	db = initTestDB()
+	r := newTestRouter()
+	r.GET("/order/:id", getOrder)
+	r.DELETE("/order/:id", deleteOrder)
+	r.POST("/order/:id/restore", restoreOrder)
+
+	order := Order{MenuID: 1, Quantity: 1, TableID: 1, Status: "В процессе"}
+	db.Create(&order)
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/order/%d", order.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Мягко удалённый заказ не возвращается
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/order/%d", order.ID), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/order/%d/restore", order.ID), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/order/%d", order.ID), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCreateOrderOutOfStock(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 1})
+	r := newTestRouter()
+	r.POST("/order", createOrder)
+
+	orderJSON, _ := json.Marshal(Order{MenuID: 1, Quantity: 2, TableID: 1})
+	req, _ := http.NewRequest("POST", "/order", bytes.NewBuffer(orderJSON))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCancelOrder(t *testing.T) {
+	db = initTestDB()
+	stock := startFakeMenu(t, map[uint]int{1: 5})
+	r := newTestRouter()
+	r.POST("/order/:id/cancel", cancelOrder)
+
+	order := Order{MenuID: 1, Quantity: 2, TableID: 1, Status: "В процессе"}
+	db.Create(&order)
+
+	body, _ := json.Marshal(map[string]interface{}{"reason": "guest_changed_mind", "cancelled_by": "waiter1"})
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/order/%d/cancel", order.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 7, stock[1])
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "Отменен", response["order"].(map[string]interface{})["status"])
+	assert.Equal(t, "waiter1", response["order"].(map[string]interface{})["cancelled_by"])
+
+	// Повторная отмена невозможна
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/order/%d/cancel", order.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = serveWithSpec(t, r, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// ID из пути разбирается до запроса к базе: строка не попадает в SQL
+func TestCancelOrderInvalidID(t *testing.T) {
+	r := newTestRouter()
+	r.POST("/order/:id/cancel", cancelOrder)
+
+	body, _ := json.Marshal(map[string]interface{}{"reason": "guest_changed_mind"})
+	req, _ := http.NewRequest("POST", "/order/1%20OR%201=1/cancel", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	// Запрос заведомо не соответствует спецификации, поэтому без serveWithSpec
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_id")
+}
+
+func TestCancelCookedOrderIsWasted(t *testing.T) {
+	db = initTestDB()
+	menu := startFakeMenuServer(t, &fakeMenu{stock: map[uint]int{1: 5}, consumed: map[uint]int{}})
+	r := newTestRouter()
+	r.POST("/order/:id/cancel", cancelOrder)
+
+	order := Order{MenuID: 1, Quantity: 2, TableID: 1, Status: "В процессе"}
+	db.Create(&order)
+
+	body, _ := json.Marshal(map[string]interface{}{"reason": "kitchen_error", "cancelled_by": "chef", "cooked": true})
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/order/%d/cancel", order.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 5, menu.stock[1])
+	// Резерв не повисает: приготовленные порции списываются
+	assert.Equal(t, 2, menu.consumed[1])
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, true, response["order"].(map[string]interface{})["wasted"])
+}
+
+func TestModifyOrder(t *testing.T) {
+	db = initTestDB()
+	stock := startFakeMenu(t, map[uint]int{1: 5, 2: 5})
+	r := newTestRouter()
+	r.PATCH("/order/:id", modifyOrder)
+	r.GET("/order/:id/history", getOrderHistory)
+
+	order := Order{MenuID: 1, Quantity: 2, TableID: 1, Status: "В ожидании"}
+	db.Create(&order)
+
+	body, _ := json.Marshal(map[string]interface{}{"menu_id": 2, "quantity": 3, "notes": "без лука", "changed_by": "waiter1"})
+	req, _ := http.NewRequest("PATCH", fmt.Sprintf("/order/%d", order.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 7, stock[1])
+	assert.Equal(t, 2, stock[2])
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, float64(3), response["order"].(map[string]interface{})["quantity"])
+	assert.Equal(t, "без лука", response["order"].(map[string]interface{})["notes"])
+
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/order/%d/history", order.ID), nil)
+	w = serveWithSpec(t, r, req)
+
+	var history []OrderHistory
+	json.Unmarshal(w.Body.Bytes(), &history)
+	assert.Len(t, history, 1)
+	assert.Equal(t, "modified", history[0].Action)
+	assert.Equal(t, "waiter1", history[0].ChangedBy)
+}
+
+// Пересадка гостей: новый стол занят, прежний без других заказов освобождается
+func TestModifyOrderMovesTable(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 5})
+	r := newTestRouter()
+	r.PATCH("/order/:id", modifyOrder)
+
+	from := createTestTable(t, 711, 4)
+	to := createTestTable(t, 712, 4)
+	order := Order{MenuID: 1, Quantity: 1, TableID: from.ID, Status: statusPending}
+	db.Create(&order)
+	db.First(&from, from.ID)
+	assert.Equal(t, tableDining, from.State)
+
+	body, _ := json.Marshal(map[string]interface{}{"table_id": to.ID})
+	req, _ := http.NewRequest("PATCH", fmt.Sprintf("/order/%d", order.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&from, from.ID)
+	db.First(&to, to.ID)
+	assert.Equal(t, tableFree, from.State)
+	assert.Equal(t, tableDining, to.State)
+}
+
+func TestModifyOrderAfterCookingStarted(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 5})
+	r := newTestRouter()
+	r.PATCH("/order/:id", modifyOrder)
+
+	order := Order{MenuID: 1, Quantity: 2, TableID: 1, Status: "В процессе"}
+	db.Create(&order)
+
+	body, _ := json.Marshal(map[string]interface{}{"quantity": 3})
+	req, _ := http.NewRequest("PATCH", fmt.Sprintf("/order/%d", order.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, r, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreateOrderWithModifiers(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 10})
+	r := newTestRouter()
+	r.POST("/order", createOrder)
+	r.GET("/order/:id/ticket", getKitchenTicket)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"menu_id": 1, "quantity": 2, "table_id": 3,
+		"modifier_ids": []uint{1, 3}, "notes": "подать горячим",
+	})
+	req, _ := http.NewRequest("POST", "/order", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Order Order `json:"order"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 150.0, response.Order.UnitPrice)
+	assert.Equal(t, 300.0, response.Order.TotalPrice)
+	assert.Len(t, response.Order.Modifiers, 2)
+
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/order/%d/ticket", response.Order.ID), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Без сметаны")
+	assert.Contains(t, w.Body.String(), "подать горячим")
+}
+
+func TestCreateOrderWithTwoSizes(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 10})
+	r := newTestRouter()
+	r.POST("/order", createOrder)
+
+	body, _ := json.Marshal(map[string]interface{}{"menu_id": 1, "quantity": 1, "table_id": 1, "modifier_ids": []uint{1, 2}})
+	req, _ := http.NewRequest("POST", "/order", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOrderNumbersAreUniqueUnderConcurrency(t *testing.T) {
+	db = initTestDB()
+
+	const workers = 20
+	numbers := make(chan string, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order := Order{MenuID: 1, Quantity: 1, TableID: 1, Status: "В ожидании"}
+			if err := db.Create(&order).Error; err == nil {
+				numbers <- order.OrderNumber
+			}
+		}()
+	}
+	wg.Wait()
+	close(numbers)
+
+	seen := map[string]bool{}
+	for number := range numbers {
+		assert.False(t, seen[number], "номер %s выдан дважды", number)
+		seen[number] = true
+	}
+	assert.Len(t, seen, workers)
+}
+
+// Служебные поля из тела запроса игнорируются
+func TestCreateOrderIgnoresServerFields(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 10})
+	r := newTestRouter()
+	r.POST("/order", createOrder)
+
+	body := `{"menu_id": 1, "quantity": 1, "table_id": 1, "ID": 424242, "version": 7, "status": "Завершен",
+		"cancel_reason": "kitchen_error", "wasted": true, "total_price": 1}`
+	req, _ := http.NewRequest("POST", "/order", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Order Order `json:"order"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NotEqual(t, uint(424242), response.Order.ID)
+	assert.Equal(t, 1, response.Order.Version)
+	assert.Equal(t, statusPending, response.Order.Status)
+	assert.Empty(t, response.Order.CancelReason)
+	assert.False(t, response.Order.Wasted)
+	assert.Equal(t, 100.0, response.Order.TotalPrice)
+}
+
+func TestCreateOrderRejectsClientNumber(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 10})
+	r := newTestRouter()
+	r.POST("/order", createOrder)
+
+	body, _ := json.Marshal(map[string]interface{}{"order_number": "MY-1", "menu_id": 1, "quantity": 1, "table_id": 1})
+	req, _ := http.NewRequest("POST", "/order", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFormatOrderNumber(t *testing.T) {
+	day := time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "ORD20250307-007", formatOrderNumber(day, 7))
+}
+
+func TestBusinessDay(t *testing.T) {
+	businessDayStart = 6
+	defer func() { businessDayStart = 0 }()
+
+	// В 2 часа ночи ещё идёт предыдущий рабочий день
+	night := time.Date(2025, 3, 8, 2, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC), businessDay(night))
+}
+
+func TestCreateOrderIdempotent(t *testing.T) {
+	db = initTestDB()
+	stock := startFakeMenu(t, map[uint]int{1: 10})
+	r := newTestRouter()
+	r.POST("/order", idempotent(), createOrder)
+
+	key := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	body, _ := json.Marshal(map[string]interface{}{"menu_id": 1, "quantity": 2, "table_id": 1})
+
+	send := func(payload []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/order", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send(body)
+	second := send(body)
+
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	// Порции зарезервированы только один раз
+	assert.Equal(t, 8, stock[1])
+
+	// Тот же ключ с другим телом — конфликт
+	otherBody, _ := json.Marshal(map[string]interface{}{"menu_id": 1, "quantity": 3, "table_id": 1})
+	third := send(otherBody)
+	assert.Equal(t, http.StatusUnprocessableEntity, third.Code)
+}
+
+func TestUpdateOrderStatusStaleIfMatch(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 10})
+	r := newTestRouter()
+	r.GET("/order/:id", getOrder)
+	r.PUT("/order/:id/status", UpdateOrderStatus)
+
+	order := Order{MenuID: 1, Quantity: 2, TableID: 1, Status: "В ожидании"}
+	db.Create(&order)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/order/%d", order.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	update := func(status string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"status": status})
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/order/%d/status", order.ID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", etag)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Второй повар отправляет изменение по устаревшей версии
+	assert.Equal(t, http.StatusOK, update("В процессе").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, update("Завершен").Code)
+}
+
+// Кухня взяла заказ — порции списываются один раз; отмена возвращает их на склад
+func TestStartCookingConsumesStock(t *testing.T) {
+	db = initTestDB()
+	menu := startFakeMenuServer(t, &fakeMenu{stock: map[uint]int{1: 5}, consumed: map[uint]int{}})
+	r := newTestRouter()
+	r.PUT("/order/:id/status", UpdateOrderStatus)
+	r.POST("/order/:id/cancel", cancelOrder)
+
+	order := Order{MenuID: 1, Quantity: 2, TableID: 1, Status: statusPending}
+	db.Create(&order)
+
+	setStatus := func(status string) {
+		body, _ := json.Marshal(map[string]string{"status": status})
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/order/%d/status", order.ID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	setStatus(statusInProgress)
+	setStatus(statusPending)
+	setStatus(statusInProgress)
+	assert.Equal(t, 2, menu.consumed[1])
+
+	body, _ := json.Marshal(map[string]interface{}{"reason": "guest_changed_mind", "cancelled_by": "waiter1"})
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/order/%d/cancel", order.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, menu.consumed[1])
+	assert.Equal(t, 7, menu.stock[1])
+}
+
+// Блюдо вне расписания заказать нельзя, но уже принятый заказ на него можно менять
+func TestCreateOrderOutsideSchedule(t *testing.T) {
+	db = initTestDB()
+	menu := startFakeMenuServer(t, &fakeMenu{stock: map[uint]int{1: 10, 2: 10}, consumed: map[uint]int{}, closed: map[uint]bool{2: true}})
+	r := newTestRouter()
+	r.POST("/order", createOrder)
+	r.PATCH("/order/:id", modifyOrder)
+
+	body, _ := json.Marshal(map[string]interface{}{"menu_id": 2, "quantity": 1, "table_id": 1})
+	req, _ := http.NewRequest("POST", "/order", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, r, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "dish_outside_schedule")
+	assert.Equal(t, 10, menu.stock[2])
+
+	order := Order{MenuID: 1, Quantity: 1, TableID: 1, Status: statusPending, UnitPrice: 100, TotalPrice: 100}
+	db.Create(&order)
+	menu.mu.Lock()
+	menu.closed[1] = true
+	menu.mu.Unlock()
+
+	body, _ = json.Marshal(map[string]interface{}{"quantity": 2, "changed_by": "waiter1"})
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/order/%d", order.ID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = serveWithSpec(t, r, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCreateOrderValidation(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 10})
+	r := newTestRouter()
+	r.POST("/order", createOrder)
+
+	body, _ := json.Marshal(map[string]interface{}{"menu_id": 1, "quantity": 0, "table_id": 1})
+	req, _ := http.NewRequest("POST", "/order", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Error apierr.Response `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "validation_failed", response.Error.Code)
+	assert.Equal(t, "Validation failed", response.Error.Message)
+	assert.Equal(t, "must be at least 1", response.Error.Fields["quantity"])
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	assert.NoError(t, err)
+	assert.NoError(t, doc.Validate(context.Background()))
+
+	r := NewRouter()
+
+	// Каждый маршрут сервиса описан в спецификации
+	for _, route := range r.Routes() {
+		req, _ := http.NewRequest(route.Method, strings.ReplaceAll(route.Path, ":id", "1"), nil)
+		_, _, err := specRouter.FindRoute(req)
+		assert.NoError(t, err, "%s %s нет в openapi.json", route.Method, route.Path)
+	}
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := serveWithSpec(t, r, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, string(openAPISpec), w.Body.String())
+}
+
+func TestGRPCCreateAndCancelOrder(t *testing.T) {
+	db = initTestDB()
+	stock := startFakeMenu(t, map[uint]int{1: 5})
+	client := orderv1.NewOrderServiceClient(serveInMemory(t, NewGRPCServer()))
+	ctx := context.Background()
+
+	order, err := client.CreateOrder(ctx, &orderv1.CreateOrderRequest{MenuId: 1, Quantity: 2, TableId: 3, ModifierIds: []uint32{1}})
+	assert.NoError(t, err)
+	assert.Equal(t, "В ожидании", order.GetStatus())
+	assert.Equal(t, 300.0, order.GetTotalPrice())
+	assert.NotEmpty(t, order.GetOrderNumber())
+	assert.Equal(t, 3, stock[1])
+
+	// Ошибки валидации и устаревшая версия — те же, что и в REST
+	_, err = client.CreateOrder(ctx, &orderv1.CreateOrderRequest{MenuId: 1, Quantity: 0, TableId: 3})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.CancelOrder(ctx, &orderv1.CancelOrderRequest{Id: order.GetId(), Reason: "kitchen_error", CancelledBy: "waiter1", Version: order.GetVersion() + 1})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	cancelled, err := client.CancelOrder(ctx, &orderv1.CancelOrderRequest{Id: order.GetId(), Reason: "guest_changed_mind", CancelledBy: "waiter1", Version: order.GetVersion()})
+	assert.NoError(t, err)
+	assert.True(t, cancelled.GetStockReturned())
+	assert.Equal(t, "Отменен", cancelled.GetOrder().GetStatus())
+	assert.Equal(t, 5, stock[1])
+}
+
+func TestHealthz(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	w := serveWithSpec(t, NewRouter(), req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+// Завершённые заказы в прошлом, чтобы не смешиваться с данными других тестов
+func seedReportOrders(t *testing.T) {
+	day := time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)
+	db.Unscoped().Where("created_at >= ? AND created_at < ?", day, day.AddDate(0, 0, 1)).Delete(&Order{})
+	orders := []Order{
+		{MenuID: 1, Quantity: 2, TableID: 1, Status: statusCompleted, TotalPrice: 200, CreatedAt: day.Add(12 * time.Hour)},
+		{MenuID: 3, Quantity: 1, TableID: 1, Status: statusCompleted, TotalPrice: 150, CreatedAt: day.Add(12*time.Hour + 20*time.Minute)},
+		{MenuID: 1, Quantity: 1, TableID: 1, Status: statusCompleted, TotalPrice: 100, CreatedAt: day.Add(19 * time.Hour)},
+		{MenuID: 2, Quantity: 3, TableID: 2, Status: statusCompleted, TotalPrice: 300, CreatedAt: day.Add(13 * time.Hour)},
+		{MenuID: 2, Quantity: 5, TableID: 2, Status: statusCancelled, TotalPrice: 500, CreatedAt: day.Add(14 * time.Hour)},
+	}
+	for i := range orders {
+		if err := db.Create(&orders[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func reportRequest(path string) *http.Request {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("X-User-Role", "manager")
+	return req
+}
+
+func TestRevenueReport(t *testing.T) {
+	db = initTestDB()
+	seedReportOrders(t)
+
+	w := serveWithSpec(t, NewRouter(), reportRequest("/reports/revenue?from=2001-02-03&to=2001-02-03&group_by=hour"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rows []revenueRow
+	json.Unmarshal(w.Body.Bytes(), &rows)
+	// Отменённый заказ в выручку не входит
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, 12, rows[0].Period.Hour())
+		assert.Equal(t, int64(2), rows[0].Orders)
+		assert.Equal(t, 350.0, rows[0].Revenue)
+	}
+}
+
+func TestDishReportCSV(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 10, 2: 10, 3: 10})
+	seedReportOrders(t)
+
+	w := serveWithSpec(t, NewRouter(), reportRequest("/reports/dishes?from=2001-02-03&to=2001-02-03&format=csv"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	assert.Equal(t, "menu_id,name,category_id,category,quantity,revenue\n"+
+		"1,Блюдо 1,1,Супы,3,300.00\n"+
+		"2,Блюдо 2,1,Супы,3,300.00\n"+
+		"3,Блюдо 3,2,Горячее,1,150.00\n", w.Body.String())
+}
+
+// Продажи удалённого блюда остаются в отчёте с его названием и категорией
+func TestDishReportIncludesDeletedDishes(t *testing.T) {
+	db = initTestDB()
+	startFakeMenuServer(t, &fakeMenu{stock: map[uint]int{1: 10, 2: 10, 3: 10}, consumed: map[uint]int{}, deleted: map[uint]bool{3: true}})
+	seedReportOrders(t)
+
+	w := serveWithSpec(t, NewRouter(), reportRequest("/reports/dishes?from=2001-02-03&to=2001-02-03&format=csv"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "3,Блюдо 3,2,Горячее,1,150.00\n")
+}
+
+func TestCategoryReport(t *testing.T) {
+	db = initTestDB()
+	startFakeMenu(t, map[uint]int{1: 10, 2: 10, 3: 10})
+	seedReportOrders(t)
+
+	w := serveWithSpec(t, NewRouter(), reportRequest("/reports/categories?from=2001-02-03&to=2001-02-03"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"category_id": 1, "category": "Супы", "quantity": 6, "revenue": 600},
+		{"category_id": 2, "category": "Горячее", "quantity": 1, "revenue": 150}
+	]`, w.Body.String())
+}
+
+// Оплаты столов в прошлом; средний чек и оборачиваемость считаются по ним, а не по заказам
+func seedReportPayments(t *testing.T) {
+	day := time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)
+	db.Where("created_at >= ? AND created_at < ?", day, day.AddDate(0, 0, 1)).Delete(&Payment{})
+	payments := []Payment{
+		{TableID: 1, Tender: tenderCard, Subtotal: 350, Discount: 50, Amount: 300, PaidBy: "waiter1", CreatedAt: day.Add(13 * time.Hour)},
+		{TableID: 1, Tender: tenderCash, Subtotal: 100, Amount: 100, PaidBy: "waiter1", CreatedAt: day.Add(20 * time.Hour)},
+		{TableID: 2, Tender: tenderCash, Subtotal: 300, Amount: 300, PaidBy: "waiter2", CreatedAt: day.Add(14 * time.Hour)},
+	}
+	for i := range payments {
+		if err := db.Create(&payments[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAverageCheckAndTableTurnover(t *testing.T) {
+	db = initTestDB()
+	seedReportPayments(t)
+	router := NewRouter()
+
+	// Стол 1 оплатил обед со скидкой и ужин — два визита; стол 2 — один визит
+	w := serveWithSpec(t, router, reportRequest("/reports/average-check?from=2001-02-03&to=2001-02-03"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"checks": 3, "revenue": 700, "average_check": 233.33}`, w.Body.String())
+
+	w = serveWithSpec(t, router, reportRequest("/reports/tables?from=2001-02-03&to=2001-02-03"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"table_id": 1, "visits": 2, "visits_per_day": 2, "revenue": 400},
+		{"table_id": 2, "visits": 1, "visits_per_day": 1, "revenue": 300}
+	]`, w.Body.String())
+}
+
+func TestReportsRequireManager(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/reports/revenue", nil)
+	w := serveWithSpec(t, NewRouter(), req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestReportQueryValidation(t *testing.T) {
+	// Запрос заведомо не соответствует спецификации, поэтому без serveWithSpec
+	w := httptest.NewRecorder()
+	NewRouter().ServeHTTP(w, reportRequest("/reports/revenue?from=2001-02-05&to=2001-02-03&format=xml"))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Contains(t, response["error"]["fields"], "to")
+	assert.Contains(t, response["error"]["fields"], "format")
+}
+
+// Открывает новую смену, закрывая оставшиеся от других тестов; по окончании теста смена закрывается
+func openTestShift(t *testing.T, router http.Handler, openingCash float64) Shift {
+	db.Exec("UPDATE shifts SET closed_at = now() WHERE closed_at IS NULL")
+	t.Cleanup(func() { db.Exec("UPDATE shifts SET closed_at = now() WHERE closed_at IS NULL") })
+
+	body, _ := json.Marshal(map[string]interface{}{"opening_cash": openingCash, "opened_by": "manager1"})
+	w := serveWithSpec(t, router, managerRequest("POST", "/shifts", body))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var shift Shift
+	json.Unmarshal(w.Body.Bytes(), &shift)
+	return shift
+}
+
+func managerRequest(method, path string, body []byte) *http.Request {
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Role", "manager")
+	return req
+}
+
+func TestShiftWorkflow(t *testing.T) {
+	db = initTestDB()
+	router := NewRouter()
+	shift := openTestShift(t, router, 1000)
+
+	// Вторая смена не открывается, пока открыта первая
+	body, _ := json.Marshal(map[string]interface{}{"opening_cash": 0, "opened_by": "manager2"})
+	w := serveWithSpec(t, router, managerRequest("POST", "/shifts", body))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	paid := Order{MenuID: 1, Quantity: 3, TableID: 901, Status: statusCompleted, TotalPrice: 300}
+	open := Order{MenuID: 1, Quantity: 1, TableID: 902, Status: statusPending, TotalPrice: 100}
+	db.Create(&paid)
+	db.Create(&open)
+	if assert.NotNil(t, paid.ShiftID) {
+		assert.Equal(t, shift.ID, *paid.ShiftID)
+	}
+
+	// Стол 902 ещё не рассчитался — смену закрыть нельзя
+	body, _ = json.Marshal(map[string]interface{}{"counted_cash": 1200, "closed_by": "manager1"})
+	w = serveWithSpec(t, router, managerRequest("POST", "/shifts/current/close", body))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	body, _ = json.Marshal(map[string]interface{}{"table_id": 901, "tender": "cash", "discount": 50, "paid_by": "waiter1"})
+	w = serveWithSpec(t, router, managerRequest("POST", "/payments", body))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var payment Payment
+	json.Unmarshal(w.Body.Bytes(), &payment)
+	assert.Equal(t, 250.0, payment.Amount)
+	assert.Equal(t, []uint{paid.ID}, payment.OrderIDs)
+
+	// Оплаченный заказ больше не меняется
+	body, _ = json.Marshal(map[string]interface{}{"status": statusInProgress})
+	w = serveWithSpec(t, router, managerRequest("PUT", fmt.Sprintf("/order/%d/status", paid.ID), body))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	db.Model(&open).Updates(map[string]interface{}{"status": statusCancelled, "wasted": true})
+
+	body, _ = json.Marshal(map[string]interface{}{"counted_cash": 1200, "closed_by": "manager1"})
+	w = serveWithSpec(t, router, managerRequest("POST", "/shifts/current/close", body))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Отчёт сохранён вместе со сменой
+	w = serveWithSpec(t, router, managerRequest("GET", fmt.Sprintf("/shifts/%d", shift.ID), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var closed Shift
+	json.Unmarshal(w.Body.Bytes(), &closed)
+	assert.NotNil(t, closed.ClosedAt)
+	if assert.NotNil(t, closed.Report) {
+		report := closed.Report
+		assert.Equal(t, int64(1), report.Orders)
+		assert.Equal(t, 300.0, report.GrossSales)
+		assert.Equal(t, 50.0, report.Discounts)
+		assert.Equal(t, 250.0, report.NetSales)
+		assert.Equal(t, []tenderTotal{{Tender: tenderCash, Payments: 1, Amount: 250}}, report.Tenders)
+		assert.Equal(t, cancellationTotal{Orders: 1, Amount: 100, Wasted: 1}, report.Cancellations)
+		assert.Equal(t, 1250.0, report.ExpectedCash)
+		// В кассе на 50 меньше, чем должно быть
+		assert.Equal(t, -50.0, *report.CashDiscrepancy)
+	}
+}
+
+func TestPaymentRequiresOpenShift(t *testing.T) {
+	db = initTestDB()
+	db.Exec("UPDATE shifts SET closed_at = now() WHERE closed_at IS NULL")
+
+	body, _ := json.Marshal(map[string]interface{}{"table_id": 901, "tender": "card", "paid_by": "waiter1"})
+	w := serveWithSpec(t, NewRouter(), managerRequest("POST", "/payments", body))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// Заказ, принятый до открытия смены, оплачивается в открытой позже смене и переходит в неё
+func TestPaymentAttachesOrdersWithoutShift(t *testing.T) {
+	db = initTestDB()
+	router := NewRouter()
+	db.Exec("UPDATE shifts SET closed_at = now() WHERE closed_at IS NULL")
+	db.Where("table_id = ? AND payment_id IS NULL", 903).Delete(&Order{})
+
+	orphan := Order{MenuID: 1, Quantity: 2, TableID: 903, Status: statusCompleted, TotalPrice: 200}
+	db.Create(&orphan)
+	assert.Nil(t, orphan.ShiftID)
+
+	shift := openTestShift(t, router, 0)
+	body, _ := json.Marshal(map[string]interface{}{"table_id": 903, "tender": "card", "paid_by": "waiter1"})
+	w := serveWithSpec(t, router, managerRequest("POST", "/payments", body))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var payment Payment
+	json.Unmarshal(w.Body.Bytes(), &payment)
+	assert.Equal(t, []uint{orphan.ID}, payment.OrderIDs)
+	assert.Equal(t, 200.0, payment.Amount)
+
+	db.First(&orphan, orphan.ID)
+	if assert.NotNil(t, orphan.ShiftID) {
+		assert.Equal(t, shift.ID, *orphan.ShiftID)
+	}
+	assert.NotNil(t, orphan.PaymentID)
+}
+
+func TestShiftsRequireManager(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{"opening_cash": 100, "opened_by": "waiter1"})
+	req, _ := http.NewRequest("POST", "/shifts", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serveWithSpec(t, NewRouter(), req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// Стол для тестов броней; оставшийся от прошлых запусков удаляется вместе с бронями
+func createTestTable(t *testing.T, number, seats int) Table {
+	var old Table
+	if db.Where("number = ?", number).Find(&old); old.ID != 0 {
+		db.Where("table_id = ?", old.ID).Delete(&Reservation{})
+		db.Delete(&old)
+	}
+	table := Table{Number: number, Seats: seats, State: tableFree}
+	if err := db.Create(&table).Error; err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+func reserve(t *testing.T, router http.Handler, input map[string]interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(input)
+	req, _ := http.NewRequest("POST", "/reservations", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	return serveWithSpec(t, router, req)
+}
+
+func TestReservationConflicts(t *testing.T) {
+	db = initTestDB()
+	router := NewRouter()
+	table := createTestTable(t, 701, 4)
+	evening := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+
+	w := reserve(t, router, map[string]interface{}{"table_id": table.ID, "guest_name": "Иванов", "party_size": 4, "starts_at": evening})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Пересечение с существующей бронью
+	w = reserve(t, router, map[string]interface{}{"table_id": table.ID, "guest_name": "Петров", "party_size": 2, "starts_at": evening.Add(time.Hour)})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Сразу после окончания брони стол свободен
+	w = reserve(t, router, map[string]interface{}{"table_id": table.ID, "guest_name": "Петров", "party_size": 2, "starts_at": evening.Add(reservationDuration)})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Гостей больше, чем мест
+	w = reserve(t, router, map[string]interface{}{"table_id": table.ID, "guest_name": "Сидоров", "party_size": 6, "starts_at": evening.Add(24 * time.Hour)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Contains(t, response["error"]["fields"], "party_size")
+}
+
+func TestReservationArrivalAndFirstOrder(t *testing.T) {
+	db = initTestDB()
+	router := NewRouter()
+	table := createTestTable(t, 702, 2)
+
+	w := reserve(t, router, map[string]interface{}{"table_id": table.ID, "guest_name": "Иванов", "party_size": 2, "starts_at": time.Now()})
+	var reservation Reservation
+	json.Unmarshal(w.Body.Bytes(), &reservation)
+
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/reservations/%d/arrive", reservation.ID), nil)
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&table, table.ID)
+	assert.Equal(t, tableWaiting, table.State)
+
+	// Первый заказ гостей занимает стол и отмечает посадку
+	db.Create(&Order{MenuID: 1, Quantity: 1, TableID: table.ID, Status: statusPending})
+	db.First(&table, table.ID)
+	db.First(&reservation, reservation.ID)
+	assert.Equal(t, tableDining, table.State)
+	assert.Equal(t, reservationArrived, reservation.Status)
+	assert.NotNil(t, reservation.SeatedAt)
+
+	// Пришедших гостей нельзя отметить как неявку
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/reservations/%d/no-show", reservation.ID), nil)
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestReservationNoShow(t *testing.T) {
+	db = initTestDB()
+	router := NewRouter()
+	table := createTestTable(t, 703, 4)
+
+	w := reserve(t, router, map[string]interface{}{"table_id": table.ID, "guest_name": "Иванов", "party_size": 3, "starts_at": time.Now().Add(time.Hour)})
+	var upcoming Reservation
+	json.Unmarshal(w.Body.Bytes(), &upcoming)
+
+	// До начала брони неявку не отметить
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/reservations/%d/no-show", upcoming.ID), nil)
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = reserve(t, router, map[string]interface{}{"table_id": table.ID, "guest_name": "Петров", "party_size": 3, "starts_at": time.Now().Add(-4 * time.Hour), "duration_minutes": 60})
+	var missed Reservation
+	json.Unmarshal(w.Body.Bytes(), &missed)
+
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/reservations/%d/no-show", missed.ID), nil)
+	w = serveWithSpec(t, router, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &missed)
+	assert.Equal(t, reservationNoShow, missed.Status)
+}
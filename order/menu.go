@@ -0,0 +1,118 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"c_keeper_go/logging"
+	"c_keeper_go/metrics"
+	menuv1 "c_keeper_go/proto/menu/v1"
+	"c_keeper_go/tracing"
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// Соединение с gRPC API сервиса меню; устанавливается при первом вызове после Setup
+var (
+	menuConn   *grpc.ClientConn
+	menuClient menuv1.MenuServiceClient
+)
+
+// Ограничение времени одного вызова сервиса menu
+var menuCallTimeout = time.Duration(getEnvInt("MENU_CALL_TIMEOUT_SECONDS", 5)) * time.Second
+
+// Ошибки сервиса menu
+var (
+	errOutOfStock   = apierr.Conflict("not_enough_stock")
+	errDishNotFound = apierr.BadRequest("dish_not_found")
+)
+
+func connectMenu(addr string) error {
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		tracing.ClientHandler(),
+		grpc.WithChainUnaryInterceptor(logging.UnaryClientInterceptor(), metrics.UnaryClientInterceptor()),
+	)
+	if err != nil {
+		return fmt.Errorf("настройка клиента menu: %w", err)
+	}
+	menuConn, menuClient = conn, menuv1.NewMenuServiceClient(conn)
+	return nil
+}
+
+// Ошибка вызова menu в терминах сервиса заказов
+func menuError(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return errDishNotFound
+	case codes.FailedPrecondition:
+		return errOutOfStock
+	default:
+		return apierr.Upstream("menu_unavailable", err)
+	}
+}
+
+// Получение блюда с модификаторами из сервиса menu
+func fetchDish(ctx context.Context, menuID uint) (*menuv1.Dish, error) {
+	ctx, cancel := context.WithTimeout(ctx, menuCallTimeout)
+	defer cancel()
+
+	dish, err := menuClient.GetDish(ctx, &menuv1.GetDishRequest{Id: uint32(menuID)})
+	if err != nil {
+		return nil, menuError(err)
+	}
+	return dish, nil
+}
+
+// Все блюда меню по ID, включая удалённые: названия и категории для отчётов
+func fetchDishes(ctx context.Context) (map[uint]*menuv1.Dish, error) {
+	ctx, cancel := context.WithTimeout(ctx, menuCallTimeout)
+	defer cancel()
+
+	resp, err := menuClient.ListDishes(ctx, &menuv1.ListDishesRequest{IncludeDeleted: true})
+	if err != nil {
+		return nil, menuError(err)
+	}
+	dishes := make(map[uint]*menuv1.Dish, len(resp.GetDishes()))
+	for _, dish := range resp.GetDishes() {
+		dishes[uint(dish.GetId())] = dish
+	}
+	return dishes, nil
+}
+
+// Изменение остатка блюда в сервисе menu: отрицательный delta резервирует порции, положительный возвращает
+func adjustDishStock(ctx context.Context, menuID uint, delta int) error {
+	return adjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(menuID), Delta: int32(delta)})
+}
+
+// Возврат (delta > 0) или повторное списание (delta < 0) порций, уже списанных кухней:
+// у блюда с рецептом меняется остаток ингредиентов на складе, а не резерв
+func adjustConsumedStock(ctx context.Context, menuID uint, delta int) error {
+	return adjustStock(ctx, &menuv1.AdjustStockRequest{Id: uint32(menuID), Delta: int32(delta), Consumed: true})
+}
+
+func adjustStock(ctx context.Context, req *menuv1.AdjustStockRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, menuCallTimeout)
+	defer cancel()
+
+	if _, err := menuClient.AdjustStock(ctx, req); err != nil {
+		return menuError(err)
+	}
+	return nil
+}
+
+// Списание зарезервированных порций, когда кухня начала готовить заказ;
+// отрицательный quantity отменяет списание
+func consumeDishStock(ctx context.Context, menuID uint, quantity int) error {
+	ctx, cancel := context.WithTimeout(ctx, menuCallTimeout)
+	defer cancel()
+
+	_, err := menuClient.ConsumeStock(ctx, &menuv1.ConsumeStockRequest{Id: uint32(menuID), Quantity: int32(quantity)})
+	if err != nil {
+		return menuError(err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package order
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"log/slog"
+	"time"
+)
+
+// Бизнес-метрики сервиса заказов
+var (
+	ordersCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orders_created_total",
+		Help: "Созданные заказы.",
+	})
+	ordersCancelled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_cancelled_total",
+		Help: "Отменённые заказы по причинам.",
+	}, []string{"reason"})
+	orderCompletionSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "order_completion_seconds",
+		Help:    "Время от создания заказа до статуса «Завершен».",
+		Buckets: []float64{60, 300, 600, 900, 1200, 1800, 2700, 3600, 5400, 7200},
+	})
+)
+
+func init() {
+	prometheus.MustRegister(orderStatsCollector{})
+}
+
+var (
+	ordersByStatusDesc = prometheus.NewDesc("orders", "Заказы по статусам.", []string{"status"}, nil)
+	revenueDesc        = prometheus.NewDesc("order_revenue", "Выручка по завершённым заказам.", nil, nil)
+)
+
+// Число заказов по статусам и выручка считаются запросом к базе при каждом сборе метрик,
+// поэтому не сбрасываются при перезапуске сервиса
+type orderStatsCollector struct{}
+
+func (orderStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ordersByStatusDesc
+	ch <- revenueDesc
+}
+
+func (orderStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	if db == nil {
+		return
+	}
+
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Model(&Order{}).Select("status, count(*) AS count").Group("status").Scan(&counts).Error; err != nil {
+		slog.Error("ошибка подсчёта заказов для метрик", "error", err)
+		return
+	}
+	for _, row := range counts {
+		ch <- prometheus.MustNewConstMetric(ordersByStatusDesc, prometheus.GaugeValue, float64(row.Count), row.Status)
+	}
+
+	var revenue float64
+	if err := db.Model(&Order{}).Where("status = ?", statusCompleted).
+		Select("COALESCE(SUM(total_price), 0)").Scan(&revenue).Error; err != nil {
+		slog.Error("ошибка подсчёта выручки для метрик", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(revenueDesc, prometheus.GaugeValue, revenue)
+}
+
+// Учёт завершённого заказа; заказы, созданные до появления created_at, пропускаются
+func observeCompleted(order Order) {
+	if order.CreatedAt.IsZero() {
+		return
+	}
+	orderCompletionSeconds.Observe(time.Since(order.CreatedAt).Seconds())
+}
@@ -0,0 +1,124 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	menuv1 "c_keeper_go/proto/menu/v1"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Модификатор, выбранный в заказе (копия данных меню на момент заказа)
+type OrderModifier struct {
+	ID         uint    `gorm:"primaryKey" json:"id"`
+	OrderID    uint    `gorm:"index" json:"order_id"`
+	ModifierID uint    `json:"modifier_id"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	PriceDelta float64 `json:"price_delta"`
+}
+
+// Ошибка выбора модификаторов: правило валидации для поля modifier_ids
+func modifierError(rule string, param interface{}) error {
+	return apierr.Validation(apierr.FieldErrors{"modifier_ids": {Rule: rule, Param: param}})
+}
+
+// Проверка выбранных модификаторов и расчёт цены заказа по данным меню.
+// newDish — блюдо выбрано только что, и его должно быть можно заказать по расписанию меню
+func priceOrder(ctx context.Context, order *Order, newDish bool) error {
+	dish, err := fetchDish(ctx, order.MenuID)
+	if err != nil {
+		return err
+	}
+	if newDish && dish.GetOutsideSchedule() {
+		return apierr.Conflict("dish_outside_schedule", dish.GetName())
+	}
+
+	available := make(map[uint]*menuv1.Modifier, len(dish.GetModifiers()))
+	for _, modifier := range dish.GetModifiers() {
+		available[uint(modifier.GetId())] = modifier
+	}
+
+	chosen := make(map[uint]bool, len(order.ModifierIDs))
+	modifiers := make([]OrderModifier, 0, len(order.ModifierIDs))
+	unitPrice := dish.GetPrice()
+	sizes := 0
+	for _, id := range order.ModifierIDs {
+		modifier, ok := available[id]
+		if !ok {
+			return modifierError("modifier_unknown", id)
+		}
+		if chosen[id] {
+			return modifierError("modifier_duplicate", id)
+		}
+		chosen[id] = true
+		if modifier.GetType() == "size" {
+			sizes++
+		}
+		unitPrice += modifier.GetPriceDelta()
+		modifiers = append(modifiers, OrderModifier{
+			ModifierID: id,
+			Name:       modifier.GetName(),
+			Type:       modifier.GetType(),
+			PriceDelta: modifier.GetPriceDelta(),
+		})
+	}
+	if sizes > 1 {
+		return modifierError("modifier_one_size", nil)
+	}
+
+	order.Modifiers = modifiers
+	order.UnitPrice = unitPrice
+	order.TotalPrice = unitPrice * float64(order.Quantity)
+	return nil
+}
+
+// Кухонный тикет: что и для какого стола готовить
+func getKitchenTicket(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	var order Order
+
+	if err := requestDB(c).Preload("Modifiers").First(&order, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "order_not_found"))
+		return
+	}
+
+	dish, err := fetchDish(c.Request.Context(), order.MenuID)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+
+	modifiers := make([]string, 0, len(order.Modifiers))
+	for _, modifier := range order.Modifiers {
+		modifiers = append(modifiers, modifier.Name)
+	}
+
+	// Строка для печати на кухне, например "Стол 3: Борщ x2 (Без сметаны) — подать горячим"
+	line := fmt.Sprintf("Стол %d: %s x%d", order.TableID, dish.GetName(), order.Quantity)
+	if len(modifiers) > 0 {
+		line += fmt.Sprintf(" (%s)", strings.Join(modifiers, ", "))
+	}
+	if order.Notes != "" {
+		line += " — " + order.Notes
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order_id":     order.ID,
+		"order_number": order.OrderNumber,
+		"table_id":     order.TableID,
+		"dish":         dish.GetName(),
+		"quantity":     order.Quantity,
+		"modifiers":    modifiers,
+		"notes":        order.Notes,
+		"status":       order.Status,
+		"text":         line,
+	})
+}
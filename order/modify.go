@@ -0,0 +1,177 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"context"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// Заказ можно менять, пока его не начали готовить
+var editableStatuses = map[string]bool{statusPending: true}
+
+// Тело запроса PATCH /order/:id — передаются только изменяемые поля
+type orderPatch struct {
+	MenuID      *uint   `json:"menu_id"`
+	Quantity    *int    `json:"quantity"`
+	TableID     *uint   `json:"table_id"`
+	Notes       *string `json:"notes"`
+	ModifierIDs *[]uint `json:"modifier_ids"`
+	ChangedBy   string  `json:"changed_by"`
+}
+
+// Изменение блюда, количества, стола или заметок заказа
+func modifyOrder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	var order Order
+
+	if err := requestDB(c).Preload("Modifiers").First(&order, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "order_not_found"))
+		return
+	}
+
+	var patch orderPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+
+	if !checkIfMatch(c, order) {
+		return
+	}
+
+	if !editableStatuses[order.Status] {
+		apierr.Abort(c, apierr.Conflict("order_not_editable"))
+		return
+	}
+
+	updated := order
+	changes := map[string]fieldChange{}
+	if patch.MenuID != nil && *patch.MenuID != order.MenuID {
+		updated.MenuID = *patch.MenuID
+		changes["menu_id"] = fieldChange{From: order.MenuID, To: updated.MenuID}
+	}
+	if patch.Quantity != nil && *patch.Quantity != order.Quantity {
+		updated.Quantity = *patch.Quantity
+		changes["quantity"] = fieldChange{From: order.Quantity, To: updated.Quantity}
+	}
+	if patch.TableID != nil && *patch.TableID != order.TableID {
+		updated.TableID = *patch.TableID
+		changes["table_id"] = fieldChange{From: order.TableID, To: updated.TableID}
+	}
+	if patch.Notes != nil && *patch.Notes != order.Notes {
+		updated.Notes = *patch.Notes
+		changes["notes"] = fieldChange{From: order.Notes, To: updated.Notes}
+	}
+
+	// Модификаторы привязаны к блюду: при смене блюда без новых модификаторов они сбрасываются
+	currentModifierIDs := make([]uint, 0, len(order.Modifiers))
+	for _, modifier := range order.Modifiers {
+		currentModifierIDs = append(currentModifierIDs, modifier.ModifierID)
+	}
+	updated.ModifierIDs = currentModifierIDs
+	modifiersChanged := false
+	if patch.ModifierIDs != nil {
+		updated.ModifierIDs = *patch.ModifierIDs
+		modifiersChanged = !slices.Equal(currentModifierIDs, updated.ModifierIDs)
+	} else if updated.MenuID != order.MenuID {
+		updated.ModifierIDs = nil
+		modifiersChanged = len(currentModifierIDs) > 0
+	}
+	if modifiersChanged {
+		changes["modifier_ids"] = fieldChange{From: currentModifierIDs, To: updated.ModifierIDs}
+	}
+
+	if errs := updated.validate(); len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	if len(changes) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": successMessage(c, "order_unchanged"), "order": order})
+		return
+	}
+
+	// Пересчитываем цену по актуальному меню
+	if updated.MenuID != order.MenuID || updated.Quantity != order.Quantity || modifiersChanged {
+		if err := priceOrder(c.Request.Context(), &updated, updated.MenuID != order.MenuID); err != nil {
+			apierr.Abort(c, err)
+			return
+		}
+		if updated.TotalPrice != order.TotalPrice {
+			changes["total_price"] = fieldChange{From: order.TotalPrice, To: updated.TotalPrice}
+		}
+	}
+
+	if err := reserveChangedStock(c.Request.Context(), order, updated); err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+
+	err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := saveOrderVersioned(tx, &updated); err != nil {
+			return err
+		}
+		if modifiersChanged || updated.MenuID != order.MenuID {
+			if err := tx.Where("order_id = ?", order.ID).Delete(&OrderModifier{}).Error; err != nil {
+				return err
+			}
+			for i := range updated.Modifiers {
+				updated.Modifiers[i].ID = 0
+				updated.Modifiers[i].OrderID = order.ID
+			}
+			if len(updated.Modifiers) > 0 {
+				if err := tx.Create(&updated.Modifiers).Error; err != nil {
+					return err
+				}
+			}
+		}
+		// Гости пересели: новый стол занят, старый освобождается, если на нём не осталось заказов
+		if updated.TableID != order.TableID {
+			if err := seatTable(tx, updated.TableID); err != nil {
+				return err
+			}
+			if err := releaseTableIfSettled(tx, order.TableID); err != nil {
+				return err
+			}
+		}
+		return recordHistory(tx, order.ID, historyActionModified, changes, patch.ChangedBy)
+	})
+	if err != nil {
+		// Откатываем резерв, заказ остался прежним
+		reserveChangedStock(context.WithoutCancel(c.Request.Context()), updated, order)
+		apierr.Abort(c, err)
+		return
+	}
+
+	c.Header("ETag", orderETag(updated))
+	c.JSON(http.StatusOK, gin.H{"message": successMessage(c, "order_updated"), "order": updated})
+}
+
+// Перерезервирование порций при смене блюда или количества
+func reserveChangedStock(ctx context.Context, before, after Order) error {
+	if before.MenuID == after.MenuID {
+		delta := after.Quantity - before.Quantity
+		if delta == 0 {
+			return nil
+		}
+		return adjustDishStock(ctx, after.MenuID, -delta)
+	}
+
+	// Сначала резервируем новое блюдо, затем возвращаем старое
+	if err := adjustDishStock(ctx, after.MenuID, -after.Quantity); err != nil {
+		return err
+	}
+	if err := adjustDishStock(ctx, before.MenuID, before.Quantity); err != nil {
+		adjustDishStock(context.WithoutCancel(ctx), after.MenuID, after.Quantity)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package order
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// Счётчик заказов за рабочий день
+type OrderCounter struct {
+	Day  string `gorm:"primaryKey;type:date"`
+	Last int
+}
+
+// Формат номера заказа: {prefix}, {date} (ГГГГММДД) и {seq} (порядковый номер за день)
+var (
+	orderNumberPrefix   = getEnv("ORDER_NUMBER_PREFIX", "ORD")
+	orderNumberFormat   = getEnv("ORDER_NUMBER_FORMAT", "{prefix}{date}-{seq}")
+	orderNumberSeqWidth = getEnvInt("ORDER_NUMBER_SEQ_WIDTH", 3)
+	businessDayStart    = getEnvInt("BUSINESS_DAY_START_HOUR", 0) // час начала рабочего дня
+)
+
+// Рабочий день, к которому относится момент t: заказы после полуночи,
+// но до начала нового рабочего дня, относятся к предыдущему
+func businessDay(t time.Time) time.Time {
+	shifted := t.Add(-time.Duration(businessDayStart) * time.Hour)
+	return time.Date(shifted.Year(), shifted.Month(), shifted.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Следующий номер заказа. Счётчик увеличивается одним UPSERT-запросом,
+// поэтому параллельные createOrder не получат одинаковые номера
+func nextOrderNumber(tx *gorm.DB, now time.Time) (string, error) {
+	day := businessDay(now)
+
+	var seq int
+	err := tx.Raw(`INSERT INTO order_counters (day, last) VALUES (?, 1)
+		ON CONFLICT (day) DO UPDATE SET last = order_counters.last + 1
+		RETURNING last`, day.Format("2006-01-02")).Scan(&seq).Error
+	if err != nil {
+		return "", fmt.Errorf("не удалось получить номер заказа: %w", err)
+	}
+
+	return formatOrderNumber(day, seq), nil
+}
+
+func formatOrderNumber(day time.Time, seq int) string {
+	return strings.NewReplacer(
+		"{prefix}", orderNumberPrefix,
+		"{date}", day.Format("20060102"),
+		"{seq}", fmt.Sprintf("%0*d", orderNumberSeqWidth, seq),
+	).Replace(orderNumberFormat)
+}
+
+// Номер и смена назначаются при создании заказа в той же транзакции
+func (o *Order) BeforeCreate(tx *gorm.DB) error {
+	if err := attachShift(tx, o); err != nil {
+		return err
+	}
+	if o.OrderNumber != "" {
+		return nil
+	}
+	number, err := nextOrderNumber(tx, time.Now())
+	if err != nil {
+		return err
+	}
+	o.OrderNumber = number
+	return nil
+}
+
+// Старые числовые номера не уникальны: переводим колонку в строку
+// и заменяем их на LEGACY-<id>, чтобы можно было создать уникальный индекс
+func migrateOrderNumbers(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&Order{}, "order_number") {
+		return nil
+	}
+	columnTypes, err := db.Migrator().ColumnTypes(&Order{})
+	if err != nil {
+		return err
+	}
+	for _, column := range columnTypes {
+		if column.Name() != "order_number" {
+			continue
+		}
+		if strings.Contains(strings.ToLower(column.DatabaseTypeName()), "int") {
+			return db.Exec(`ALTER TABLE orders ALTER COLUMN order_number TYPE varchar(64) USING 'LEGACY-' || id`).Error
+		}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package order
+
+import (
+	_ "embed"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Спецификация OpenAPI 3 сервиса; по ней же проверяются запросы и ответы в тестах
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// Отдача спецификации (GET /openapi.json)
+func getOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
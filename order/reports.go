@@ -0,0 +1,373 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"encoding/csv"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Часовой пояс ресторана: по нему считаются дни и часы в отчётах
+var reportLocation = loadReportLocation(getEnv("REPORT_TIMEZONE", "UTC"))
+
+func loadReportLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// Параметры отчёта: период [from, to) и формат ответа
+type reportQuery struct {
+	From   time.Time
+	To     time.Time
+	Format string
+}
+
+// Разбор ?from=&to=&format=. Даты — YYYY-MM-DD (в часовом поясе ресторана) или RFC 3339;
+// по умолчанию — текущие сутки, to=дата включает весь этот день
+func parseReportQuery(c *gin.Context) (reportQuery, apierr.FieldErrors) {
+	errs := apierr.FieldErrors{}
+	now := time.Now().In(reportLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, reportLocation)
+	query := reportQuery{From: today, To: today.AddDate(0, 0, 1), Format: c.DefaultQuery("format", "json")}
+
+	parse := func(field string, target *time.Time, dayEnd bool) {
+		value := c.Query(field)
+		if value == "" {
+			return
+		}
+		if day, err := time.ParseInLocation(time.DateOnly, value, reportLocation); err == nil {
+			if dayEnd {
+				day = day.AddDate(0, 0, 1)
+			}
+			*target = day
+			return
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			errs.Add(field, "invalid_type", nil)
+			return
+		}
+		*target = parsed
+	}
+	parse("from", &query.From, false)
+	parse("to", &query.To, true)
+
+	if len(errs) == 0 && !query.To.After(query.From) {
+		errs.Add("to", "after_from", nil)
+	}
+	if query.Format != "json" && query.Format != "csv" {
+		errs.Add("format", "one_of", "json, csv")
+	}
+	return query, errs
+}
+
+// Завершённые заказы за период; выручку дают только они
+func completedOrders(c *gin.Context, query reportQuery) *gorm.DB {
+	return requestDB(c).Model(&Order{}).
+		Where("status = ? AND created_at >= ? AND created_at < ?", statusCompleted, query.From, query.To)
+}
+
+// Общая часть обработчиков: только менеджер, разбор параметров
+func startReport(c *gin.Context) (reportQuery, bool) {
+	if !isManager(c) {
+		apierr.Abort(c, apierr.Forbidden("reports_forbidden"))
+		return reportQuery{}, false
+	}
+	query, errs := parseReportQuery(c)
+	if len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return query, false
+	}
+	return query, true
+}
+
+// Ответ отчёта: JSON как есть или CSV с заголовком и строками
+func writeReport(c *gin.Context, query reportQuery, name string, body interface{}, header []string, rows [][]string) {
+	if query.Format != "csv" {
+		c.JSON(http.StatusOK, body)
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s_%s.csv"`,
+		name, query.From.In(reportLocation).Format(time.DateOnly), query.To.In(reportLocation).Format(time.DateOnly)))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(header)
+	w.WriteAll(rows)
+}
+
+func formatMoney(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// Округление до копеек, чтобы в JSON не попадали хвосты float
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// Выручка за период
+type revenueRow struct {
+	Period  time.Time `json:"period"` // начало дня или часа
+	Orders  int64     `json:"orders"`
+	Revenue float64   `json:"revenue"`
+}
+
+// Выручка по дням или часам (GET /reports/revenue?group_by=day|hour)
+func getRevenueReport(c *gin.Context) {
+	query, ok := startReport(c)
+	if !ok {
+		return
+	}
+	groupBy := c.DefaultQuery("group_by", "day")
+	if groupBy != "day" && groupBy != "hour" {
+		errs := apierr.FieldErrors{}
+		errs.Add("group_by", "one_of", "day, hour")
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	// Группировка в часовом поясе ресторана
+	var rows []struct {
+		Period  time.Time
+		Orders  int64
+		Revenue float64
+	}
+	err := completedOrders(c, query).
+		Select("date_trunc(?, created_at AT TIME ZONE ?) AS period, count(*) AS orders, COALESCE(SUM(total_price), 0) AS revenue",
+			groupBy, reportLocation.String()).
+		Group("period").Order("period").
+		Scan(&rows).Error
+	if err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+
+	report := make([]revenueRow, len(rows))
+	records := make([][]string, len(rows))
+	for i, row := range rows {
+		// AT TIME ZONE возвращает время без пояса, драйвер читает его как UTC
+		period := time.Date(row.Period.Year(), row.Period.Month(), row.Period.Day(), row.Period.Hour(), 0, 0, 0, reportLocation)
+		report[i] = revenueRow{Period: period, Orders: row.Orders, Revenue: roundMoney(row.Revenue)}
+		records[i] = []string{period.Format(time.RFC3339), strconv.FormatInt(row.Orders, 10), formatMoney(row.Revenue)}
+	}
+	writeReport(c, query, "revenue", report, []string{"period", "orders", "revenue"}, records)
+}
+
+// Продажи блюда за период
+type dishSalesRow struct {
+	MenuID     uint    `json:"menu_id"`
+	Name       string  `json:"name"`
+	CategoryID uint    `json:"category_id"`
+	Category   string  `json:"category"`
+	Quantity   int64   `json:"quantity"`
+	Revenue    float64 `json:"revenue"`
+}
+
+// Продажи по блюдам с названиями и категориями из сервиса menu
+func dishSales(c *gin.Context, query reportQuery) ([]dishSalesRow, error) {
+	var rows []struct {
+		MenuID   uint
+		Quantity int64
+		Revenue  float64
+	}
+	err := completedOrders(c, query).
+		Select("menu_id, SUM(quantity) AS quantity, COALESCE(SUM(total_price), 0) AS revenue").
+		Group("menu_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, apierr.Internal(err)
+	}
+	if len(rows) == 0 {
+		return []dishSalesRow{}, nil
+	}
+
+	dishes, err := fetchDishes(c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	sales := make([]dishSalesRow, len(rows))
+	for i, row := range rows {
+		sales[i] = dishSalesRow{MenuID: row.MenuID, Quantity: row.Quantity, Revenue: roundMoney(row.Revenue)}
+		// Удалённых блюд в меню уже нет — остаются только ID
+		if dish, ok := dishes[row.MenuID]; ok {
+			sales[i].Name = dish.GetName()
+			sales[i].CategoryID = uint(dish.GetCategoryId())
+			sales[i].Category = dish.GetCategory().GetName()
+		}
+	}
+
+	// Самые продаваемые — первыми
+	sort.Slice(sales, func(i, j int) bool {
+		if sales[i].Quantity != sales[j].Quantity {
+			return sales[i].Quantity > sales[j].Quantity
+		}
+		return sales[i].MenuID < sales[j].MenuID
+	})
+	return sales, nil
+}
+
+// Количество проданных порций по блюдам (GET /reports/dishes)
+func getDishReport(c *gin.Context) {
+	query, ok := startReport(c)
+	if !ok {
+		return
+	}
+	sales, err := dishSales(c, query)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+
+	records := make([][]string, len(sales))
+	for i, row := range sales {
+		records[i] = []string{
+			strconv.FormatUint(uint64(row.MenuID), 10), row.Name,
+			strconv.FormatUint(uint64(row.CategoryID), 10), row.Category,
+			strconv.FormatInt(row.Quantity, 10), formatMoney(row.Revenue),
+		}
+	}
+	writeReport(c, query, "dishes", sales,
+		[]string{"menu_id", "name", "category_id", "category", "quantity", "revenue"}, records)
+}
+
+// Продажи категории за период
+type categorySalesRow struct {
+	CategoryID uint    `json:"category_id"`
+	Category   string  `json:"category"`
+	Quantity   int64   `json:"quantity"`
+	Revenue    float64 `json:"revenue"`
+}
+
+// Количество проданных порций по категориям (GET /reports/categories)
+func getCategoryReport(c *gin.Context) {
+	query, ok := startReport(c)
+	if !ok {
+		return
+	}
+	sales, err := dishSales(c, query)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+
+	// Индексы, а не указатели: append может переложить срез
+	byCategory := map[uint]int{}
+	var report []categorySalesRow
+	for _, dish := range sales {
+		i, ok := byCategory[dish.CategoryID]
+		if !ok {
+			i = len(report)
+			byCategory[dish.CategoryID] = i
+			report = append(report, categorySalesRow{CategoryID: dish.CategoryID, Category: dish.Category})
+		}
+		report[i].Quantity += dish.Quantity
+		report[i].Revenue = roundMoney(report[i].Revenue + dish.Revenue)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Quantity > report[j].Quantity })
+	if report == nil {
+		report = []categorySalesRow{}
+	}
+
+	records := make([][]string, len(report))
+	for i, row := range report {
+		records[i] = []string{
+			strconv.FormatUint(uint64(row.CategoryID), 10), row.Category,
+			strconv.FormatInt(row.Quantity, 10), formatMoney(row.Revenue),
+		}
+	}
+	writeReport(c, query, "categories", report, []string{"category_id", "category", "quantity", "revenue"}, records)
+}
+
+// Визиты столов за период: визит — одна оплата стола. Выручка считается по оплатам
+// со скидками, как в Z-отчёте смены
+type tableVisits struct {
+	TableID uint    `json:"table_id"`
+	Visits  int64   `json:"visits"`
+	Revenue float64 `json:"revenue"`
+}
+
+func visitsByTable(c *gin.Context, query reportQuery) ([]tableVisits, error) {
+	var rows []tableVisits
+	err := requestDB(c).Model(&Payment{}).
+		Select("table_id, COUNT(*) AS visits, COALESCE(SUM(amount), 0) AS revenue").
+		Where("created_at >= ? AND created_at < ?", query.From, query.To).
+		Group("table_id").Order("table_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, apierr.Internal(err)
+	}
+	for i := range rows {
+		rows[i].Revenue = roundMoney(rows[i].Revenue)
+	}
+	return rows, nil
+}
+
+// Средний чек (GET /reports/average-check): оплаченная сумма, делённая на число счетов
+func getAverageCheckReport(c *gin.Context) {
+	query, ok := startReport(c)
+	if !ok {
+		return
+	}
+	tables, err := visitsByTable(c, query)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+
+	var visits int64
+	var revenue, average float64
+	for _, table := range tables {
+		visits += table.Visits
+		revenue += table.Revenue
+	}
+	if visits > 0 {
+		average = revenue / float64(visits)
+	}
+
+	report := gin.H{"checks": visits, "revenue": roundMoney(revenue), "average_check": roundMoney(average)}
+	writeReport(c, query, "average_check", report,
+		[]string{"checks", "revenue", "average_check"},
+		[][]string{{strconv.FormatInt(visits, 10), formatMoney(revenue), formatMoney(average)}})
+}
+
+// Оборачиваемость столов (GET /reports/tables): визиты каждого стола и в среднем за день
+type tableTurnoverRow struct {
+	tableVisits
+	VisitsPerDay float64 `json:"visits_per_day"`
+}
+
+func getTableTurnoverReport(c *gin.Context) {
+	query, ok := startReport(c)
+	if !ok {
+		return
+	}
+	tables, err := visitsByTable(c, query)
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+
+	days := math.Max(query.To.Sub(query.From).Hours()/24, 1)
+	report := make([]tableTurnoverRow, len(tables))
+	records := make([][]string, len(tables))
+	for i, table := range tables {
+		perDay := math.Round(float64(table.Visits)/days*100) / 100
+		report[i] = tableTurnoverRow{tableVisits: table, VisitsPerDay: perDay}
+		records[i] = []string{
+			strconv.FormatUint(uint64(table.TableID), 10), strconv.FormatInt(table.Visits, 10),
+			strconv.FormatFloat(perDay, 'f', 2, 64), formatMoney(table.Revenue),
+		}
+	}
+	writeReport(c, query, "tables", report, []string{"table_id", "visits", "visits_per_day", "revenue"}, records)
+}
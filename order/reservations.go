@@ -0,0 +1,233 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Бронь стола на интервал [starts_at, ends_at)
+type Reservation struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	TableID   uint       `gorm:"index" json:"table_id"`
+	GuestName string     `json:"guest_name"`
+	Phone     string     `json:"phone"`
+	PartySize int        `json:"party_size"`
+	StartsAt  time.Time  `gorm:"index" json:"starts_at"`
+	EndsAt    time.Time  `json:"ends_at"`
+	Status    string     `json:"status"`
+	Notes     string     `json:"notes"`
+	ArrivedAt *time.Time `json:"arrived_at"`
+	SeatedAt  *time.Time `json:"seated_at"` // первый заказ гостей
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Статусы брони
+const (
+	reservationBooked    = "booked"
+	reservationArrived   = "arrived"
+	reservationNoShow    = "no_show"
+	reservationCancelled = "cancelled"
+)
+
+// Бронь занимает стол, пока гости не ушли, не пришли или бронь не отменена
+var activeReservationStatuses = []string{reservationBooked, reservationArrived}
+
+// Длительность брони по умолчанию
+var reservationDuration = time.Duration(getEnvInt("RESERVATION_DURATION_MINUTES", 120)) * time.Minute
+
+// Тело запроса POST /reservations
+type reservationInput struct {
+	TableID         uint       `json:"table_id"`
+	GuestName       string     `json:"guest_name"`
+	Phone           string     `json:"phone"`
+	PartySize       int        `json:"party_size"`
+	StartsAt        *time.Time `json:"starts_at"`
+	DurationMinutes int        `json:"duration_minutes"` // 0 — RESERVATION_DURATION_MINUTES
+	Notes           string     `json:"notes"`
+}
+
+func (r reservationInput) validate() apierr.FieldErrors {
+	errs := apierr.FieldErrors{}
+	if r.TableID == 0 {
+		errs.Add("table_id", "required", nil)
+	}
+	validateString(errs, "guest_name", r.GuestName, true, 255)
+	validateString(errs, "phone", r.Phone, false, 32)
+	if r.PartySize < 1 {
+		errs.Add("party_size", "min", 1)
+	}
+	if r.StartsAt == nil {
+		errs.Add("starts_at", "required", nil)
+	}
+	if r.DurationMinutes != 0 && r.DurationMinutes < 15 {
+		errs.Add("duration_minutes", "min", 15)
+	}
+	validateString(errs, "notes", r.Notes, false, 500)
+	return errs
+}
+
+// Бронирование стола (POST /reservations). Стол должен вмещать гостей
+// и быть свободен от других активных броней на этот интервал
+func createReservation(c *gin.Context) {
+	var input reservationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	if errs := input.validate(); len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	duration := reservationDuration
+	if input.DurationMinutes > 0 {
+		duration = time.Duration(input.DurationMinutes) * time.Minute
+	}
+	reservation := Reservation{
+		TableID:   input.TableID,
+		GuestName: strings.TrimSpace(input.GuestName),
+		Phone:     input.Phone,
+		PartySize: input.PartySize,
+		StartsAt:  *input.StartsAt,
+		EndsAt:    input.StartsAt.Add(duration),
+		Status:    reservationBooked,
+		Notes:     input.Notes,
+	}
+
+	err := requestDB(c).Transaction(func(tx *gorm.DB) error {
+		// Блокировка стола упорядочивает параллельные брони одного стола
+		var table Table
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&table, reservation.TableID).Error; err != nil {
+			return dbError(err, "table_not_found")
+		}
+		if reservation.PartySize > table.Seats {
+			errs := apierr.FieldErrors{}
+			errs.Add("party_size", "max", table.Seats)
+			return apierr.Validation(errs)
+		}
+
+		var conflicting Reservation
+		err := tx.Where("table_id = ? AND status IN ? AND starts_at < ? AND ends_at > ?",
+			reservation.TableID, activeReservationStatuses, reservation.EndsAt, reservation.StartsAt).
+			Order("starts_at").Limit(1).Find(&conflicting).Error
+		if err != nil {
+			return apierr.Internal(err)
+		}
+		if conflicting.ID != 0 {
+			return apierr.Conflict("reservation_conflict", conflicting.StartsAt.In(reportLocation).Format("2006-01-02 15:04"))
+		}
+
+		if err := tx.Create(&reservation).Error; err != nil {
+			return apierr.Internal(err)
+		}
+		return nil
+	})
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// Брони (GET /reservations). Фильтры: ?date=YYYY-MM-DD (в часовом поясе ресторана), ?table_id=, ?status=
+func getReservations(c *gin.Context) {
+	query := requestDB(c).Order("starts_at, id")
+	if date := c.Query("date"); date != "" {
+		day, err := time.ParseInLocation(time.DateOnly, date, reportLocation)
+		if err != nil {
+			errs := apierr.FieldErrors{}
+			errs.Add("date", "invalid_type", nil)
+			apierr.Abort(c, apierr.Validation(errs))
+			return
+		}
+		query = query.Where("starts_at >= ? AND starts_at < ?", day, day.AddDate(0, 0, 1))
+	}
+	if tableID := c.Query("table_id"); tableID != "" {
+		query = query.Where("table_id = ?", tableID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var reservations []Reservation
+	if err := query.Find(&reservations).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, reservations)
+}
+
+// Смена статуса брони: загружает бронь и проверяет, что она ещё ожидает гостей
+func updateReservation(c *gin.Context, apply func(tx *gorm.DB, reservation *Reservation) error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	var reservation Reservation
+	err = requestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, id).Error; err != nil {
+			return dbError(err, "reservation_not_found")
+		}
+		if reservation.Status != reservationBooked {
+			return apierr.Conflict("reservation_not_booked")
+		}
+		if err := apply(tx, &reservation); err != nil {
+			return err
+		}
+		if err := tx.Save(&reservation).Error; err != nil {
+			return apierr.Internal(err)
+		}
+		return nil
+	})
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, reservation)
+}
+
+// Гости пришли и рассажены за забронированный стол (POST /reservations/:id/arrive)
+func arriveReservation(c *gin.Context) {
+	updateReservation(c, func(tx *gorm.DB, reservation *Reservation) error {
+		var table Table
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&table, reservation.TableID).Error; err != nil {
+			return dbError(err, "table_not_found")
+		}
+		if table.State != tableFree {
+			return apierr.Conflict("table_occupied")
+		}
+		if err := tx.Model(&table).Update("state", tableWaiting).Error; err != nil {
+			return apierr.Internal(err)
+		}
+		now := time.Now()
+		reservation.Status = reservationArrived
+		reservation.ArrivedAt = &now
+		return nil
+	})
+}
+
+// Гости не пришли (POST /reservations/:id/no-show); отметить можно только после начала брони
+func noShowReservation(c *gin.Context) {
+	updateReservation(c, func(tx *gorm.DB, reservation *Reservation) error {
+		if time.Now().Before(reservation.StartsAt) {
+			return apierr.Conflict("reservation_not_started")
+		}
+		reservation.Status = reservationNoShow
+		return nil
+	})
+}
+
+// Отмена брони (POST /reservations/:id/cancel)
+func cancelReservation(c *gin.Context) {
+	updateReservation(c, func(tx *gorm.DB, reservation *Reservation) error {
+		reservation.Status = reservationCancelled
+		return nil
+	})
+}
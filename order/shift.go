@@ -0,0 +1,436 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Кассовая смена: все заказы и оплаты между открытием и закрытием относятся к ней
+type Shift struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	OpenedAt    time.Time    `json:"opened_at"`
+	OpenedBy    string       `json:"opened_by"`
+	OpeningCash float64      `json:"opening_cash"` // наличные в кассе на начало смены
+	ClosedAt    *time.Time   `json:"closed_at"`
+	ClosedBy    string       `json:"closed_by,omitempty"`
+	CountedCash *float64     `json:"counted_cash"`                             // наличные, пересчитанные при закрытии
+	Report      *ShiftReport `gorm:"type:jsonb;serializer:json" json:"report"` // Z-отчёт; у открытой смены — текущие итоги
+}
+
+// Оплата счёта стола: закрывает все завершённые и ещё не оплаченные заказы стола в смене
+type Payment struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ShiftID   uint      `gorm:"index" json:"shift_id"`
+	TableID   uint      `json:"table_id"`
+	Tender    string    `json:"tender"`   // способ оплаты: cash, card
+	Subtotal  float64   `json:"subtotal"` // сумма заказов до скидки
+	Discount  float64   `json:"discount"`
+	Amount    float64   `json:"amount"` // к оплате: subtotal - discount
+	PaidBy    string    `json:"paid_by"`
+	OrderIDs  []uint    `gorm:"-" json:"order_ids"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Способы оплаты
+const (
+	tenderCash = "cash"
+	tenderCard = "card"
+)
+
+var validTenders = map[string]bool{tenderCash: true, tenderCard: true}
+
+// Z-отчёт смены
+type ShiftReport struct {
+	Orders          int64             `json:"orders"` // оплаченные заказы
+	Checks          int64             `json:"checks"` // оплаченные счета
+	GrossSales      float64           `json:"gross_sales"`
+	Discounts       float64           `json:"discounts"`
+	NetSales        float64           `json:"net_sales"` // gross_sales - discounts
+	Tenders         []tenderTotal     `json:"tenders"`
+	Cancellations   cancellationTotal `json:"cancellations"`
+	OpeningCash     float64           `json:"opening_cash"`
+	ExpectedCash    float64           `json:"expected_cash"`    // opening_cash + оплаты наличными
+	CountedCash     *float64          `json:"counted_cash"`     // только у закрытой смены
+	CashDiscrepancy *float64          `json:"cash_discrepancy"` // counted_cash - expected_cash: минус — недостача
+}
+
+// Продажи по способу оплаты
+type tenderTotal struct {
+	Tender   string  `json:"tender"`
+	Payments int64   `json:"payments"`
+	Amount   float64 `json:"amount"`
+}
+
+// Отменённые за смену заказы
+type cancellationTotal struct {
+	Orders int64   `json:"orders"`
+	Amount float64 `json:"amount"`
+	Wasted int64   `json:"wasted"` // уже приготовленные и списанные
+}
+
+// Открытой может быть только одна смена: уникальный индекс по условию closed_at IS NULL
+func migrateShifts(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Shift{}, &Payment{}); err != nil {
+		return err
+	}
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_shifts_single_open ON shifts ((closed_at IS NULL)) WHERE closed_at IS NULL`).Error
+}
+
+// ID открытой смены или nil. Блокировка FOR SHARE не даёт закрыть смену,
+// пока в неё записываются заказы и оплаты той же транзакцией
+func currentShiftID(tx *gorm.DB) (*uint, error) {
+	var shifts []Shift
+	err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+		Select("id").Where("closed_at IS NULL").Limit(1).Find(&shifts).Error
+	if err != nil || len(shifts) == 0 {
+		return nil, err
+	}
+	return &shifts[0].ID, nil
+}
+
+// Заказ относится к открытой смене; если смена не открыта, он остаётся без смены
+// и попадает в смену, в которой его оплатят
+func attachShift(tx *gorm.DB, order *Order) error {
+	if order.ShiftID != nil {
+		return nil
+	}
+	shiftID, err := currentShiftID(tx)
+	if err != nil {
+		return fmt.Errorf("не удалось определить смену: %w", err)
+	}
+	order.ShiftID = shiftID
+	return nil
+}
+
+// Смены доступны только менеджеру
+func requireManager(c *gin.Context) bool {
+	if !isManager(c) {
+		apierr.Abort(c, apierr.Forbidden("shifts_forbidden"))
+		return false
+	}
+	return true
+}
+
+// Открытие смены (POST /shifts)
+func openShift(c *gin.Context) {
+	if !requireManager(c) {
+		return
+	}
+
+	var request struct {
+		OpeningCash float64 `json:"opening_cash"`
+		OpenedBy    string  `json:"opened_by"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	errs := apierr.FieldErrors{}
+	if request.OpeningCash < 0 {
+		errs.Add("opening_cash", "min", 0)
+	}
+	validateString(errs, "opened_by", request.OpenedBy, true, 255)
+	if len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	shift := Shift{OpenedAt: time.Now(), OpenedBy: request.OpenedBy, OpeningCash: roundMoney(request.OpeningCash)}
+	result := requestDB(c).Clauses(clause.OnConflict{DoNothing: true}).Create(&shift)
+	if result.Error != nil {
+		apierr.Abort(c, apierr.Internal(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierr.Abort(c, apierr.Conflict("shift_already_open"))
+		return
+	}
+	c.JSON(http.StatusCreated, shift)
+}
+
+// Список смен, последние — первыми (GET /shifts)
+func getShifts(c *gin.Context) {
+	if !requireManager(c) {
+		return
+	}
+	var shifts []Shift
+	if err := requestDB(c).Order("opened_at DESC, id DESC").Find(&shifts).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, shifts)
+}
+
+// Смена с Z-отчётом; у открытой смены отчёт считается на текущий момент (GET /shifts/:id)
+func getShift(c *gin.Context) {
+	if !requireManager(c) {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	var shift Shift
+	if err := requestDB(c).First(&shift, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "shift_not_found"))
+		return
+	}
+	if err := withCurrentReport(requestDB(c), &shift); err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, shift)
+}
+
+// Открытая смена с текущими итогами (GET /shifts/current)
+func getCurrentShift(c *gin.Context) {
+	if !requireManager(c) {
+		return
+	}
+	var shift Shift
+	if err := requestDB(c).Where("closed_at IS NULL").First(&shift).Error; err != nil {
+		apierr.Abort(c, dbError(err, "shift_not_open"))
+		return
+	}
+	if err := withCurrentReport(requestDB(c), &shift); err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, shift)
+}
+
+// У открытой смены сохранённого отчёта ещё нет — считаем итоги на текущий момент
+func withCurrentReport(tx *gorm.DB, shift *Shift) error {
+	if shift.ClosedAt != nil {
+		return nil
+	}
+	report, err := buildShiftReport(tx, *shift)
+	if err != nil {
+		return apierr.Internal(err)
+	}
+	shift.Report = &report
+	return nil
+}
+
+// Закрытие смены с пересчётом наличных (POST /shifts/current/close).
+// Пока у столов есть неоплаченные заказы, смену закрыть нельзя
+func closeShift(c *gin.Context) {
+	if !requireManager(c) {
+		return
+	}
+
+	var request struct {
+		CountedCash *float64 `json:"counted_cash"`
+		ClosedBy    string   `json:"closed_by"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	errs := apierr.FieldErrors{}
+	if request.CountedCash == nil {
+		errs.Add("counted_cash", "required", nil)
+	} else if *request.CountedCash < 0 {
+		errs.Add("counted_cash", "min", 0)
+	}
+	validateString(errs, "closed_by", request.ClosedBy, true, 255)
+	if len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	var shift Shift
+	err := requestDB(c).Transaction(func(tx *gorm.DB) error {
+		// FOR UPDATE дожидается транзакций, которые прямо сейчас добавляют в смену заказы и оплаты
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("closed_at IS NULL").First(&shift).Error
+		if err != nil {
+			return dbError(err, "shift_not_open")
+		}
+
+		var openChecks int64
+		err = tx.Model(&Order{}).
+			Where("shift_id = ? AND status <> ? AND payment_id IS NULL", shift.ID, statusCancelled).
+			Distinct("table_id").Count(&openChecks).Error
+		if err != nil {
+			return apierr.Internal(err)
+		}
+		if openChecks > 0 {
+			return apierr.Conflict("shift_has_open_checks", openChecks)
+		}
+
+		report, err := buildShiftReport(tx, shift)
+		if err != nil {
+			return apierr.Internal(err)
+		}
+		counted := roundMoney(*request.CountedCash)
+		discrepancy := roundMoney(counted - report.ExpectedCash)
+		report.CountedCash = &counted
+		report.CashDiscrepancy = &discrepancy
+
+		now := time.Now()
+		shift.ClosedAt = &now
+		shift.ClosedBy = request.ClosedBy
+		shift.CountedCash = &counted
+		shift.Report = &report
+		if err := tx.Save(&shift).Error; err != nil {
+			return apierr.Internal(err)
+		}
+		return nil
+	})
+	if err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, shift)
+}
+
+// Итоги смены по её оплатам и отменённым заказам
+func buildShiftReport(tx *gorm.DB, shift Shift) (ShiftReport, error) {
+	report := ShiftReport{OpeningCash: shift.OpeningCash, ExpectedCash: shift.OpeningCash, Tenders: []tenderTotal{}}
+
+	var tenders []struct {
+		Tender   string
+		Payments int64
+		Subtotal float64
+		Discount float64
+		Amount   float64
+	}
+	err := tx.Model(&Payment{}).
+		Select("tender, COUNT(*) AS payments, SUM(subtotal) AS subtotal, SUM(discount) AS discount, SUM(amount) AS amount").
+		Where("shift_id = ?", shift.ID).
+		Group("tender").Order("tender").
+		Scan(&tenders).Error
+	if err != nil {
+		return report, err
+	}
+	for _, tender := range tenders {
+		report.Checks += tender.Payments
+		report.GrossSales += tender.Subtotal
+		report.Discounts += tender.Discount
+		report.Tenders = append(report.Tenders, tenderTotal{Tender: tender.Tender, Payments: tender.Payments, Amount: roundMoney(tender.Amount)})
+		if tender.Tender == tenderCash {
+			report.ExpectedCash += tender.Amount
+		}
+	}
+	report.GrossSales = roundMoney(report.GrossSales)
+	report.Discounts = roundMoney(report.Discounts)
+	report.NetSales = roundMoney(report.GrossSales - report.Discounts)
+	report.ExpectedCash = roundMoney(report.ExpectedCash)
+
+	err = tx.Model(&Order{}).Where("shift_id = ? AND payment_id IS NOT NULL", shift.ID).Count(&report.Orders).Error
+	if err != nil {
+		return report, err
+	}
+
+	err = tx.Model(&Order{}).
+		Select("COUNT(*) AS orders, COALESCE(SUM(total_price), 0) AS amount, COUNT(*) FILTER (WHERE wasted) AS wasted").
+		Where("shift_id = ? AND status = ?", shift.ID, statusCancelled).
+		Scan(&report.Cancellations).Error
+	report.Cancellations.Amount = roundMoney(report.Cancellations.Amount)
+	return report, err
+}
+
+// Оплата счёта стола в текущей смене (POST /payments)
+func createPayment(c *gin.Context) {
+	var request struct {
+		TableID  uint    `json:"table_id"`
+		Tender   string  `json:"tender"`
+		Discount float64 `json:"discount"`
+		PaidBy   string  `json:"paid_by"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	errs := apierr.FieldErrors{}
+	if request.TableID == 0 {
+		errs.Add("table_id", "required", nil)
+	}
+	if !validTenders[request.Tender] {
+		errs.Add("tender", "one_of", tenderCash+", "+tenderCard)
+	}
+	if request.Discount < 0 {
+		errs.Add("discount", "min", 0)
+	}
+	validateString(errs, "paid_by", request.PaidBy, true, 255)
+	if len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	payment := Payment{TableID: request.TableID, Tender: request.Tender, Discount: roundMoney(request.Discount), PaidBy: request.PaidBy}
+	if err := payTable(c.Request.Context(), &payment); err != nil {
+		apierr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, payment)
+}
+
+// Привязка завершённых неоплаченных заказов стола к новой оплате. Заказы, принятые
+// без открытой смены, оплачиваются в текущей и переходят в неё вместе с выручкой
+func payTable(ctx context.Context, payment *Payment) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		shiftID, err := currentShiftID(tx)
+		if err != nil {
+			return apierr.Internal(err)
+		}
+		if shiftID == nil {
+			return apierr.Conflict("shift_not_open")
+		}
+		payment.ShiftID = *shiftID
+
+		var orders []Order
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("(shift_id = ? OR shift_id IS NULL) AND table_id = ? AND status = ? AND payment_id IS NULL", payment.ShiftID, payment.TableID, statusCompleted).
+			Order("id").Find(&orders).Error
+		if err != nil {
+			return apierr.Internal(err)
+		}
+		if len(orders) == 0 {
+			return apierr.Conflict("nothing_to_pay", payment.TableID)
+		}
+
+		payment.OrderIDs = make([]uint, len(orders))
+		for i, order := range orders {
+			payment.OrderIDs[i] = order.ID
+			payment.Subtotal += order.TotalPrice
+		}
+		payment.Subtotal = roundMoney(payment.Subtotal)
+		if payment.Discount > payment.Subtotal {
+			errs := apierr.FieldErrors{}
+			errs.Add("discount", "max", payment.Subtotal)
+			return apierr.Validation(errs)
+		}
+		payment.Amount = roundMoney(payment.Subtotal - payment.Discount)
+
+		if err := tx.Create(payment).Error; err != nil {
+			return apierr.Internal(err)
+		}
+		// Версия растёт, чтобы изменения по устаревшему ETag не затёрли отметку об оплате
+		err = tx.Model(&Order{}).Where("id IN ?", payment.OrderIDs).
+			Updates(map[string]interface{}{"payment_id": payment.ID, "shift_id": payment.ShiftID, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return apierr.Internal(err)
+		}
+		if err := releaseTableIfSettled(tx, payment.TableID); err != nil {
+			return apierr.Internal(err)
+		}
+		return nil
+	})
+}
+
+// Оплаченный заказ входит в отчёт смены и больше не меняется
+func checkNotPaid(order Order) error {
+	if order.PaymentID != nil {
+		return apierr.Conflict("order_paid")
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+// Стол зала
+type Table struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	Number int    `gorm:"uniqueIndex;not null" json:"number"`
+	Seats  int    `gorm:"not null;default:4" json:"seats"`
+	State  string `gorm:"not null;default:free" json:"state"`
+}
+
+// Состояния стола
+const (
+	tableFree    = "free"    // свободен
+	tableWaiting = "waiting" // гости по брони рассажены, заказов ещё нет
+	tableDining  = "dining"  // гости сделали первый заказ
+)
+
+// Стол с первым заказом становится занятым. Выполняется в транзакции создания заказа
+func (o *Order) AfterCreate(tx *gorm.DB) error {
+	return seatTable(tx, o.TableID)
+}
+
+// Стол становится занятым; если за ним ждали заказа гости по брони,
+// у последней такой брони отмечается время посадки
+func seatTable(tx *gorm.DB, tableID uint) error {
+	result := tx.Model(&Table{}).Where("id = ? AND state = ?", tableID, tableWaiting).Update("state", tableDining)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return tx.Exec(`UPDATE reservations SET seated_at = now() WHERE id = (
+			SELECT id FROM reservations WHERE table_id = ? AND status = ? ORDER BY arrived_at DESC LIMIT 1
+		) AND seated_at IS NULL`, tableID, reservationArrived).Error
+	}
+	return tx.Model(&Table{}).Where("id = ? AND state = ?", tableID, tableFree).Update("state", tableDining).Error
+}
+
+// Неоплаченные заказы стола, включая принятые без открытой смены
+func openTableOrders(tx *gorm.DB, tableID uint) (int64, error) {
+	var open int64
+	err := tx.Model(&Order{}).
+		Where("table_id = ? AND status <> ? AND payment_id IS NULL", tableID, statusCancelled).
+		Count(&open).Error
+	return open, err
+}
+
+// Стол освобождается, когда у него не осталось неоплаченных заказов
+func releaseTableIfSettled(tx *gorm.DB, tableID uint) error {
+	open, err := openTableOrders(tx, tableID)
+	if err != nil || open > 0 {
+		return err
+	}
+	return tx.Model(&Table{}).Where("id = ?", tableID).Update("state", tableFree).Error
+}
+
+// Добавление стола (POST /tables, только менеджер)
+func createTable(c *gin.Context) {
+	if !isManager(c) {
+		apierr.Abort(c, apierr.Forbidden("tables_forbidden"))
+		return
+	}
+
+	var table Table
+	if err := c.ShouldBindJSON(&table); err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_body"))
+		return
+	}
+	errs := apierr.FieldErrors{}
+	if table.Number < 1 {
+		errs.Add("number", "min", 1)
+	}
+	if table.Seats < 1 {
+		errs.Add("seats", "min", 1)
+	}
+	if len(errs) > 0 {
+		apierr.Abort(c, apierr.Validation(errs))
+		return
+	}
+
+	var existing int64
+	if err := requestDB(c).Model(&Table{}).Where("number = ?", table.Number).Count(&existing).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	if existing > 0 {
+		apierr.Abort(c, apierr.Conflict("table_number_taken", table.Number))
+		return
+	}
+
+	table.ID = 0
+	table.State = tableFree
+	if err := requestDB(c).Create(&table).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusCreated, table)
+}
+
+// Столы с текущим состоянием (GET /tables)
+func getTables(c *gin.Context) {
+	var tables []Table
+	if err := requestDB(c).Order("number").Find(&tables).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, tables)
+}
+
+// Освобождение стола вручную, например если гости ушли без заказа (POST /tables/:id/release)
+func releaseTable(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apierr.Abort(c, apierr.BadRequest("invalid_id"))
+		return
+	}
+	var table Table
+	if err := requestDB(c).First(&table, id).Error; err != nil {
+		apierr.Abort(c, dbError(err, "table_not_found"))
+		return
+	}
+
+	open, err := openTableOrders(requestDB(c), table.ID)
+	if err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	if open > 0 {
+		apierr.Abort(c, apierr.Conflict("table_has_open_orders"))
+		return
+	}
+
+	if err := requestDB(c).Model(&table).Update("state", tableFree).Error; err != nil {
+		apierr.Abort(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, table)
+}
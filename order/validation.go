@@ -0,0 +1,33 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"strings"
+	"unicode/utf8"
+)
+
+// Проверка строки: обязательность и максимальная длина
+func validateString(errs apierr.FieldErrors, field, value string, required bool, maxLength int) {
+	if required && strings.TrimSpace(value) == "" {
+		errs.Add(field, "required", nil)
+		return
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		errs.Add(field, "max_length", maxLength)
+	}
+}
+
+func (o Order) validate() apierr.FieldErrors {
+	errs := apierr.FieldErrors{}
+	if o.MenuID == 0 {
+		errs.Add("menu_id", "required", nil)
+	}
+	if o.Quantity < 1 {
+		errs.Add("quantity", "min", 1)
+	}
+	if o.TableID == 0 {
+		errs.Add("table_id", "required", nil)
+	}
+	validateString(errs, "notes", o.Notes, false, 500)
+	return errs
+}
@@ -0,0 +1,61 @@
+package order
+
+import (
+	"c_keeper_go/apierr"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// Заказ изменили после того, как клиент его прочитал
+var errStaleOrder = apierr.PreconditionFailed("order_modified")
+
+// ETag заказа — его версия в кавычках
+func orderETag(order Order) string {
+	return fmt.Sprintf(`"%d"`, order.Version)
+}
+
+// Проверка If-Match: true, если заголовка нет или он совпадает с текущей версией.
+// При несовпадении сразу отвечает 412
+func checkIfMatch(c *gin.Context, order Order) bool {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == orderETag(order) {
+			return true
+		}
+	}
+	c.Header("ETag", orderETag(order))
+	apierr.Abort(c, errStaleOrder)
+	return false
+}
+
+// Проверка версии из gRPC-запроса, аналог If-Match: 0 — без проверки
+func checkVersion(order Order, version int32) error {
+	if version != 0 && int(version) != order.Version {
+		return errStaleOrder
+	}
+	return nil
+}
+
+// Сохранение заказа, только если версия в базе не изменилась с момента чтения
+func saveOrderVersioned(tx *gorm.DB, order *Order) error {
+	previous := order.Version
+	order.Version++
+	result := tx.Model(&Order{}).
+		Where("id = ? AND version = ?", order.ID, previous).
+		Select("*").Omit("id", "Modifiers").
+		Updates(order)
+	if result.Error != nil {
+		order.Version = previous
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		order.Version = previous
+		return errStaleOrder
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: menu/v1/menu.proto
+
+// gRPC API сервиса menu. Ошибки возвращаются статусами gRPC с кодом по виду
+// ошибки (см. apierr) и локализованным сообщением по метаданным accept-language
+
+package menuv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MenuService_ListDishes_FullMethodName   = "/ckeeper.menu.v1.MenuService/ListDishes"
+	MenuService_GetDish_FullMethodName      = "/ckeeper.menu.v1.MenuService/GetDish"
+	MenuService_AdjustStock_FullMethodName  = "/ckeeper.menu.v1.MenuService/AdjustStock"
+	MenuService_ConsumeStock_FullMethodName = "/ckeeper.menu.v1.MenuService/ConsumeStock"
+)
+
+// MenuServiceClient is the client API for MenuService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MenuServiceClient interface {
+	// Всё меню
+	ListDishes(ctx context.Context, in *ListDishesRequest, opts ...grpc.CallOption) (*ListDishesResponse, error)
+	// Блюдо по ID; NOT_FOUND, если блюда нет
+	GetDish(ctx context.Context, in *GetDishRequest, opts ...grpc.CallOption) (*Dish, error)
+	// Изменение остатка порций: отрицательный delta резервирует, положительный возвращает.
+	// FAILED_PRECONDITION, если порций не хватает
+	AdjustStock(ctx context.Context, in *AdjustStockRequest, opts ...grpc.CallOption) (*Dish, error)
+	// Списание порций, которые начала готовить кухня: у блюда с рецептом резерв
+	// превращается в расход ингредиентов. Отрицательный quantity отменяет списание
+	ConsumeStock(ctx context.Context, in *ConsumeStockRequest, opts ...grpc.CallOption) (*Dish, error)
+}
+
+type menuServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMenuServiceClient(cc grpc.ClientConnInterface) MenuServiceClient {
+	return &menuServiceClient{cc}
+}
+
+func (c *menuServiceClient) ListDishes(ctx context.Context, in *ListDishesRequest, opts ...grpc.CallOption) (*ListDishesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDishesResponse)
+	err := c.cc.Invoke(ctx, MenuService_ListDishes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *menuServiceClient) GetDish(ctx context.Context, in *GetDishRequest, opts ...grpc.CallOption) (*Dish, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dish)
+	err := c.cc.Invoke(ctx, MenuService_GetDish_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *menuServiceClient) AdjustStock(ctx context.Context, in *AdjustStockRequest, opts ...grpc.CallOption) (*Dish, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dish)
+	err := c.cc.Invoke(ctx, MenuService_AdjustStock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *menuServiceClient) ConsumeStock(ctx context.Context, in *ConsumeStockRequest, opts ...grpc.CallOption) (*Dish, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dish)
+	err := c.cc.Invoke(ctx, MenuService_ConsumeStock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MenuServiceServer is the server API for MenuService service.
+// All implementations must embed UnimplementedMenuServiceServer
+// for forward compatibility.
+type MenuServiceServer interface {
+	// Всё меню
+	ListDishes(context.Context, *ListDishesRequest) (*ListDishesResponse, error)
+	// Блюдо по ID; NOT_FOUND, если блюда нет
+	GetDish(context.Context, *GetDishRequest) (*Dish, error)
+	// Изменение остатка порций: отрицательный delta резервирует, положительный возвращает.
+	// FAILED_PRECONDITION, если порций не хватает
+	AdjustStock(context.Context, *AdjustStockRequest) (*Dish, error)
+	// Списание порций, которые начала готовить кухня: у блюда с рецептом резерв
+	// превращается в расход ингредиентов. Отрицательный quantity отменяет списание
+	ConsumeStock(context.Context, *ConsumeStockRequest) (*Dish, error)
+	mustEmbedUnimplementedMenuServiceServer()
+}
+
+// UnimplementedMenuServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMenuServiceServer struct{}
+
+func (UnimplementedMenuServiceServer) ListDishes(context.Context, *ListDishesRequest) (*ListDishesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDishes not implemented")
+}
+func (UnimplementedMenuServiceServer) GetDish(context.Context, *GetDishRequest) (*Dish, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDish not implemented")
+}
+func (UnimplementedMenuServiceServer) AdjustStock(context.Context, *AdjustStockRequest) (*Dish, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdjustStock not implemented")
+}
+func (UnimplementedMenuServiceServer) ConsumeStock(context.Context, *ConsumeStockRequest) (*Dish, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConsumeStock not implemented")
+}
+func (UnimplementedMenuServiceServer) mustEmbedUnimplementedMenuServiceServer() {}
+func (UnimplementedMenuServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeMenuServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MenuServiceServer will
+// result in compilation errors.
+type UnsafeMenuServiceServer interface {
+	mustEmbedUnimplementedMenuServiceServer()
+}
+
+func RegisterMenuServiceServer(s grpc.ServiceRegistrar, srv MenuServiceServer) {
+	// If the following call pancis, it indicates UnimplementedMenuServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MenuService_ServiceDesc, srv)
+}
+
+func _MenuService_ListDishes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDishesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MenuServiceServer).ListDishes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MenuService_ListDishes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MenuServiceServer).ListDishes(ctx, req.(*ListDishesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MenuService_GetDish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDishRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MenuServiceServer).GetDish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MenuService_GetDish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MenuServiceServer).GetDish(ctx, req.(*GetDishRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MenuService_AdjustStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdjustStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MenuServiceServer).AdjustStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MenuService_AdjustStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MenuServiceServer).AdjustStock(ctx, req.(*AdjustStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MenuService_ConsumeStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConsumeStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MenuServiceServer).ConsumeStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MenuService_ConsumeStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MenuServiceServer).ConsumeStock(ctx, req.(*ConsumeStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MenuService_ServiceDesc is the grpc.ServiceDesc for MenuService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MenuService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ckeeper.menu.v1.MenuService",
+	HandlerType: (*MenuServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDishes",
+			Handler:    _MenuService_ListDishes_Handler,
+		},
+		{
+			MethodName: "GetDish",
+			Handler:    _MenuService_GetDish_Handler,
+		},
+		{
+			MethodName: "AdjustStock",
+			Handler:    _MenuService_AdjustStock_Handler,
+		},
+		{
+			MethodName: "ConsumeStock",
+			Handler:    _MenuService_ConsumeStock_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "menu/v1/menu.proto",
+}
@@ -0,0 +1,99 @@
+// Package server запускает HTTP- и gRPC-серверы сервиса и плавно останавливает их
+// по SIGINT/SIGTERM, давая начатым запросам доработать.
+package server
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Server — пара серверов одного сервиса
+type Server struct {
+	HTTP            *http.Server
+	GRPC            *grpc.Server
+	GRPCAddr        string
+	ShutdownTimeout time.Duration // сколько ждать начатые запросы при остановке
+	OnShutdown      func()        // вызывается при получении сигнала, до остановки серверов
+}
+
+// Run блокируется до сигнала остановки или ошибки запуска одного из серверов
+func (s Server) Run() error {
+	httpListener, err := net.Listen("tcp", s.HTTP.Addr)
+	if err != nil {
+		return err
+	}
+	grpcListener, err := net.Listen("tcp", s.GRPCAddr)
+	if err != nil {
+		httpListener.Close()
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return s.serve(ctx, httpListener, grpcListener)
+}
+
+func (s Server) serve(ctx context.Context, httpListener, grpcListener net.Listener) error {
+	errs := make(chan error, 2)
+	go func() {
+		if err := s.HTTP.Serve(httpListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+	go func() {
+		if err := s.GRPC.Serve(grpcListener); err != nil {
+			errs <- err
+		}
+	}()
+	slog.Info("сервис запущен", "http_addr", httpListener.Addr().String(), "grpc_addr", grpcListener.Addr().String())
+
+	select {
+	case err := <-errs:
+		s.GRPC.Stop()
+		s.HTTP.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("остановка сервиса", "timeout", s.ShutdownTimeout.String())
+	if s.OnShutdown != nil {
+		s.OnShutdown()
+	}
+	return s.shutdown()
+}
+
+// Новые подключения больше не принимаются; начатые запросы дорабатывают до таймаута,
+// после чего соединения закрываются принудительно
+func (s Server) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.GRPC.GracefulStop()
+		close(stopped)
+	}()
+
+	err := s.HTTP.Shutdown(ctx)
+	if err != nil {
+		s.HTTP.Close()
+	}
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.GRPC.Stop()
+		<-stopped
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listener
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "done")
+	})}
+	shutdownCalled := false
+	s := Server{
+		HTTP:            httpServer,
+		GRPC:            grpc.NewServer(),
+		ShutdownTimeout: 5 * time.Second,
+		OnShutdown:      func() { shutdownCalled = true },
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	httpListener := listen(t)
+	result := make(chan error)
+	go func() {
+		result <- s.serve(ctx, httpListener, listen(t))
+	}()
+
+	// Сигнал приходит, пока запрос ещё обрабатывается
+	response := make(chan string)
+	go func() {
+		resp, err := http.Get("http://" + httpListener.Addr().String())
+		if !assert.NoError(t, err) {
+			response <- ""
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+	<-started
+	stop()
+
+	assert.Equal(t, "done", <-response)
+	assert.NoError(t, <-result)
+	assert.True(t, shutdownCalled)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s := Server{
+		HTTP: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		})},
+		GRPC:            grpc.NewServer(),
+		ShutdownTimeout: 50 * time.Millisecond,
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	httpListener := listen(t)
+	result := make(chan error)
+	go func() {
+		result <- s.serve(ctx, httpListener, listen(t))
+	}()
+	go http.Get("http://" + httpListener.Addr().String())
+	<-started
+	stop()
+
+	assert.ErrorIs(t, <-result, context.DeadlineExceeded)
+}
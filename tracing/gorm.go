@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"c_keeper_go/gormhooks"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// InstrumentGORM добавляет в GORM колбэки, создающие спан на каждый запрос к базе.
+// Родительский спан берётся из контекста запроса (db.WithContext)
+func InstrumentGORM(db *gorm.DB) error {
+	tracer := otel.Tracer("c_keeper_go/tracing")
+	return gormhooks.Around(db, "tracing",
+		func(operation string, tx *gorm.DB) {
+			ctx, span := tracer.Start(tx.Statement.Context, "gorm."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation)),
+			)
+			tx.Statement.Context = ctx
+			tx.InstanceSet(spanKey, span)
+		},
+		func(operation string, tx *gorm.DB) {
+			value, ok := tx.InstanceGet(spanKey)
+			if !ok {
+				return
+			}
+			span := value.(trace.Span)
+			span.SetAttributes(
+				semconv.DBCollectionName(tx.Statement.Table),
+				semconv.DBQueryText(tx.Statement.SQL.String()),
+				attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+			)
+			if tx.Error != nil && tx.Error != gorm.ErrRecordNotFound {
+				span.RecordError(tx.Error)
+				span.SetStatus(codes.Error, tx.Error.Error())
+			}
+			span.End()
+		},
+	)
+}
@@ -0,0 +1,97 @@
+// Package tracing настраивает OpenTelemetry: провайдер трейсов с экспортом в stdout или OTLP,
+// распространение контекста W3C (traceparent) и инструментирование gin, GORM, gRPC и HTTP-клиентов.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"google.golang.org/grpc"
+	"net/http"
+	"os"
+)
+
+// Экспортёр выбирается переменной TRACING_EXPORTER
+const (
+	ExporterNone   = "none"   // трейсы не отправляются, контекст всё равно передаётся дальше
+	ExporterStdout = "stdout" // трейсы печатаются в stdout
+	ExporterOTLP   = "otlp"   // OTLP/gRPC, адрес задаётся OTEL_EXPORTER_OTLP_ENDPOINT
+)
+
+// Setup настраивает глобальный провайдер трейсов сервиса. Возвращает функцию,
+// которая отправляет накопленные спаны при остановке сервиса
+func Setup(service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, err := newExporter(os.Getenv("TRACING_EXPORTER"))
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(service),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func newExporter(name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New()
+	case ExporterOTLP:
+		return otlptracegrpc.New(context.Background())
+	default:
+		return nil, fmt.Errorf("неизвестный экспортёр трейсов %q", name)
+	}
+}
+
+// Middleware создаёт спан на каждый HTTP-запрос и продолжает трейс из заголовка traceparent
+func Middleware(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service)
+}
+
+// ServerHandler создаёт спаны для входящих вызовов gRPC
+func ServerHandler() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
+
+// ClientHandler создаёт спаны для исходящих вызовов gRPC и передаёт контекст трейса в метаданных
+func ClientHandler() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+// Transport оборачивает HTTP-транспорт: спан на каждый исходящий запрос и заголовок traceparent
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceContextPropagatesToOutboundCalls(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// Нижестоящий сервис запоминает пришедший traceparent
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	r := gin.New()
+	r.Use(Middleware("order"))
+	r.GET("/order/:id/description", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+		c.Status(http.StatusOK)
+	})
+
+	// Входящий запрос продолжает трейс вызывающей стороны
+	req, _ := http.NewRequest("GET", "/order/1/description", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, traceparent, "4bf92f3577b34da6a3ce929d0e0e4736")
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		for _, span := range spans {
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		}
+	}
+}
+
+func TestUnknownExporter(t *testing.T) {
+	_, err := newExporter("zipkin")
+	assert.Error(t, err)
+
+	exporter, err := newExporter("")
+	assert.NoError(t, err)
+	assert.Nil(t, exporter)
+}